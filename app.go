@@ -0,0 +1,166 @@
+package resource
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// defaultV3Endpoint is used to exchange installation tokens when no v3_endpoint is configured
+	defaultV3Endpoint = "https://api.github.com/"
+	// appTokenRefresh is how long before expiry an installation token is replaced
+	appTokenRefresh = 5 * time.Minute
+)
+
+// newTokenSource returns the source of the tokens used for the GitHub APIs and git, i.e. the access_token,
+// or installation tokens when authenticating as a GitHub App. The HTTP client is used to request installation tokens.
+func newTokenSource(s *Source, client *http.Client) (oauth2.TokenSource, error) {
+	if !s.appAuthentication() {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.accessToken()}), nil
+	}
+
+	key, err := parsePrivateKey(s.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private_key: %s", err)
+	}
+
+	endpoint, _ := s.endpoints()
+	if endpoint == "" {
+		endpoint = defaultV3Endpoint
+	}
+
+	return oauth2.ReuseTokenSource(nil, &appTokenSource{
+		client:         client,
+		endpoint:       strings.TrimSuffix(endpoint, "/"),
+		appID:          s.AppID,
+		installationID: s.InstallationID,
+		key:            key,
+		timeout:        s.requestTimeout(),
+	}), nil
+}
+
+// appTokenSource is an oauth2.TokenSource for the installation tokens of a GitHub App.
+// See: https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/authenticating-as-a-github-app-installation
+type appTokenSource struct {
+	client         *http.Client
+	endpoint       string
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	timeout        time.Duration
+}
+
+// Token exchanges a JWT signed by the app for an installation token. The expiry of the token is moved forward,
+// so that it is replaced well before it expires.
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	log.Println("requesting installation token:", s.installationID)
+
+	jwt, err := s.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The oauth2 transport does not pass the context of the request on to the token source
+	ctx, cancel := withTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/app/installations/%d/access_tokens", s.endpoint, s.installationID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request installation token: %s", timeoutError(ctx, "installation token request", s.timeout, err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to request installation token: %s", res.Status)
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode installation token: %s", err)
+	}
+	if body.Token == "" {
+		return nil, errors.New("failed to request installation token: empty token")
+	}
+
+	return &oauth2.Token{
+		AccessToken: body.Token,
+		Expiry:      body.ExpiresAt.Add(-appTokenRefresh),
+	}, nil
+}
+
+// jwt returns a JSON Web Token (RS256) which authenticates as the app for a few minutes.
+func (s *appTokenSource) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// Issued in the past to allow for clock drift, GitHub allows at most 10 minutes until expiry
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %s", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key, in PKCS #1 (as downloaded from GitHub) or PKCS #8 form.
+func parsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+
+	return rsaKey, nil
+}
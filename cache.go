@@ -0,0 +1,162 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultChangedFilesCacheSize is the number of cached changed files lists, unless configured
+const defaultChangedFilesCacheSize = 1000
+
+// filesCache is an on-disk cache of the changed files of pull requests. The changed files of a pull request
+// never change for a given head and base commit, so entries are keyed by the repository, PR number, head OID
+// and base OID. A nil *filesCache is a disabled cache.
+type filesCache struct {
+	cacheDir
+	repository repository
+}
+
+func newFilesCache(s Source) *filesCache {
+	if s.ChangedFilesCacheDir == "" {
+		return nil
+	}
+
+	// The cache directory may be shared by the resources of several repositories
+	r, err := parseRepository(s.Repository)
+	if err != nil {
+		log.Println("disabling changed files cache:", err)
+		return nil
+	}
+
+	size := s.ChangedFilesCacheSize
+	if size < 1 {
+		size = defaultChangedFilesCacheSize
+	}
+
+	return &filesCache{cacheDir: cacheDir{dir: s.ChangedFilesCacheDir, size: size}, repository: r}
+}
+
+func (c *filesCache) name(number int, head, base string) string {
+	return fmt.Sprintf("%s-%s-%d-%s-%s.json", c.repository.owner, c.repository.name, number, head, base)
+}
+
+// get returns the cached changed files, if any.
+func (c *filesCache) get(number int, head, base string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	name := c.name(number, head, base)
+	b, ok := c.read(name)
+	if !ok {
+		return nil, false
+	}
+
+	var files []string
+	if err := json.Unmarshal(b, &files); err != nil {
+		log.Println("ignoring invalid changed files cache entry:", name)
+		return nil, false
+	}
+
+	return files, true
+}
+
+// put stores the changed files and evicts the least recently used entries.
+func (c *filesCache) put(number int, head, base string, files []string) error {
+	if c == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+
+	return c.write(c.name(number, head, base), b)
+}
+
+// cacheDir is a directory of cache entries (.json files). The least recently used entries are evicted
+// once the directory holds more than size entries.
+type cacheDir struct {
+	dir  string
+	size int
+}
+
+// read returns the content of an entry, if any.
+func (c *cacheDir) read(name string) ([]byte, bool) {
+	p := filepath.Join(c.dir, name)
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	// Mark the entry as recently used
+	now := time.Now()
+	os.Chtimes(p, now, now)
+
+	return b, true
+}
+
+// write stores an entry and evicts the least recently used entries.
+func (c *cacheDir) write(name string, b []byte) error {
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	// Write to a temporary file first, concurrent checks may read the entry
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %s", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %s", err)
+	}
+
+	return c.evict()
+}
+
+func (c *cacheDir) evict() error {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %s", err)
+	}
+
+	entries := make([]os.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".json") {
+			entries = append(entries, info)
+		}
+	}
+	if len(entries) <= c.size {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, info := range entries[:len(entries)-c.size] {
+		err := os.Remove(filepath.Join(c.dir, info.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict cache entry: %s", err)
+		}
+	}
+
+	return nil
+}
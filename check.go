@@ -1,26 +1,63 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/telia-oss/github-pr-resource/pullrequest"
 )
 
-func findPulls(since time.Time, gh Github) ([]pullrequest.PullRequest, error) {
+// defaultChangedFilesConcurrency is the number of concurrent changed files lookups, unless configured
+const defaultChangedFilesConcurrency = 4
+
+// initialVersionsNoneLookback is how far back initial_versions: none looks for updates. It is well above the
+// default check interval (1m), so an update is seen by a check, and independent of initial_lookback (which
+// would otherwise return the existing pull requests it is meant to skip).
+const initialVersionsNoneLookback = 10 * time.Minute
+
+func findPulls(ctx context.Context, since time.Time, lookback time.Duration, gh Github) ([]pullrequest.PullRequest, error) {
 	if since.IsZero() {
 		since = time.Now().AddDate(-3, 0, 0)
+		if lookback > 0 {
+			since = time.Now().Add(-lookback)
+		}
 	}
-	return gh.ListOpenPullRequests(since)
+	return gh.ListOpenPullRequests(ctx, since)
 }
 
 // Check (business logic)
-func Check(request CheckRequest, manager Github) (CheckResponse, error) {
+func Check(ctx context.Context, request CheckRequest, manager Github) (CheckResponse, error) {
 	var response CheckResponse
 
-	pulls, err := findPulls(request.Version.UpdatedDate, manager)
+	// A version with only a PR number (e.g. `fly check-resource --from pr:123`) is resolved to its current head
+	if request.Version.PR != 0 && request.Version.Commit == "" {
+		return checkPullRequest(ctx, request, manager)
+	}
+
+	// Checks are stateless, so without a previous version the time of the first check is unknown. Instead
+	// of returning no versions until one is provided, only updates since shortly before each check count
+	// as new: existing pull requests are skipped, and the first update after that becomes the first version.
+	if request.Version.PR == 0 && request.Source.InitialVersions == InitialVersionsNone {
+		request.Version.UpdatedDate = time.Now().Add(-initialVersionsNoneLookback)
+		log.Println("no previous version, only returning updates since:", request.Version.UpdatedDate)
+	}
+
+	if request.Source.MergeQueue {
+		return checkMergeGroups(ctx, request, manager)
+	}
+
+	// Search a bit before the last version for updates which were not yet indexed or suffered from clock skew
+	since := request.Version.UpdatedDate
+	if !since.IsZero() {
+		since = since.Add(-time.Duration(request.Source.SearchOverlap))
+	}
+
+	pulls, err := findPulls(ctx, since, time.Duration(request.Source.InitialLookback), manager)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get last commits: %s", err)
 	}
@@ -30,23 +67,43 @@ func Check(request CheckRequest, manager Github) (CheckResponse, error) {
 
 	log.Println("total pulls found:", len(pulls))
 
+	var candidates []pullrequest.PullRequest
 	for _, p := range pulls {
 		log.Printf("evaluate pull: %+v\n", p)
-		if !newVersion(request, p) {
+		if lastVersion(request.Version, p) {
+			log.Println("last version found in search overlap")
+			continue
+		}
+
+		if !newVersion(request, p, since) {
 			log.Println("no new version found")
 			continue
 		}
 
-		if len(paths)+len(iPaths) > 0 {
-			log.Println("pattern/s configured")
-			p.Files, err = pullRequestFiles(p.Number, manager)
-			if err != nil {
-				return nil, err
-			}
+		candidates = append(candidates, p)
+	}
+
+	if request.Source.StackedPullRequests && !since.IsZero() {
+		dependents, err := stackedPulls(ctx, request, candidates, since, manager)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, dependents...)
+	}
+
+	if len(paths)+len(iPaths) > 0 || request.Source.SkipUnaffectedProjects {
+		log.Println("pattern/s configured")
+		cache := newFilesCache(request.Source)
+		if err := changedFiles(ctx, candidates, request.Source.ChangedFilesConcurrency, manager, cache); err != nil {
+			return nil, err
+		}
+	}
 
+	for _, p := range candidates {
+		if len(paths)+len(iPaths) > 0 {
 			log.Println("paths configured:", paths)
 			log.Println("ignore paths configured:", iPaths)
-			log.Println("changed files found:", p.Files)
+			log.Println("changed files found:", p.Number, p.Files)
 
 			switch {
 			// if `paths` is configured && NONE of the changed files match `paths` pattern/s
@@ -60,54 +117,225 @@ func Check(request CheckRequest, manager Github) (CheckResponse, error) {
 			}
 		}
 
+		if request.Source.SkipUnaffectedProjects {
+			affected := affectedProjects(request.Source.Projects, p.Files)
+			log.Println("affected projects:", affected)
+			if len(affected) == 0 {
+				log.Println("no affected projects excluded pull")
+				continue
+			}
+		}
+
 		response = append(response, NewVersion(p))
 	}
 
-	// Sort the commits by date
+	// Sort the commits by date & remove duplicates, e.g. from overlapping search pages
 	sort.Sort(response)
+	response = response.dedupe()
 
+	if request.Source.StackedPullRequests {
+		response = stackOrder(response, candidates)
+	}
+
+	if request.Source.SearchOverlap > 0 {
+		response = overlapOrder(request.Version, response)
+	}
+
+	return respond(request, response), nil
+}
+
+// overlapOrder orders the last version after the versions found in the search overlap, which were updated before
+// it, so that it remains the latest version. Concourse saves the versions it already has without duplicating them,
+// while the updates which were missed by the last check (e.g. not yet indexed) are added before the last version.
+func overlapOrder(last Version, response CheckResponse) CheckResponse {
+	if last.PR == 0 {
+		return response
+	}
+
+	var before, after CheckResponse
+	for _, v := range response {
+		if v.UpdatedDate.After(last.UpdatedDate) {
+			after = append(after, v)
+		} else {
+			before = append(before, v)
+		}
+	}
+	if len(before) == 0 {
+		return response
+	}
+
+	ordered := append(before, last)
+	return append(ordered, after...)
+}
+
+// respond returns the new versions, the last version if there are none,
+// or only the latest new version if there is no last version (unless all are requested).
+func respond(request CheckRequest, response CheckResponse) CheckResponse {
 	// If there are no new but an old version = return the old
 	if len(response) == 0 && request.Version.PR != 0 {
 		log.Println("no new versions, use old")
 		response = append(response, request.Version)
 	}
 
-	// If there are new versions and no previous = return just the latest
-	if len(response) != 0 && request.Version.PR == 0 {
+	// If there are new versions and no previous = return just the latest, unless all are requested
+	if len(response) != 0 && request.Version.PR == 0 && request.Source.InitialVersions != InitialVersionsAll {
 		response = CheckResponse{response[len(response)-1]}
 	}
 
 	log.Println("version count in response:", len(response))
 	log.Println("versions:", response)
 
-	return response, nil
+	return response
+}
+
+// checkMergeGroups returns versions for the merge groups created since the last version.
+func checkMergeGroups(ctx context.Context, r CheckRequest, manager Github) (CheckResponse, error) {
+	groups, err := manager.ListMergeGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge groups: %s", err)
+	}
+
+	log.Println("total merge groups found:", len(groups))
+
+	var response CheckResponse
+	for _, g := range groups {
+		log.Printf("evaluate merge group: %+v\n", g)
+		if r.Source.BaseBranch != "" && r.Source.BaseBranch != g.BaseRefName {
+			continue
+		}
+
+		v := Version{
+			PR:          g.Number,
+			Commit:      g.HeadRef.OID,
+			UpdatedDate: g.HeadRef.CommittedDate,
+			MergeGroup:  g.Ref,
+		}
+		if v.UpdatedDate.Before(r.Version.UpdatedDate) || v.Commit == r.Version.Commit {
+			continue
+		}
+
+		response = append(response, v)
+	}
+
+	sort.Sort(response)
+	response = response.dedupe()
+
+	return respond(r, response), nil
+}
+
+func checkPullRequest(ctx context.Context, r CheckRequest, manager Github) (CheckResponse, error) {
+	p, err := manager.GetPullRequest(ctx, r.Version.PR, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %s", err)
+	}
+
+	// time based filters are bypassed, security filters are not
+	if pullrequest.Fork(r.Source.DisableForks)(p) {
+		return nil, fmt.Errorf("pull request %d is from a fork and forks are disabled", p.Number)
+	}
+
+	log.Println("resolved pull request to head:", p.HeadRef.OID)
+
+	return CheckResponse{NewVersion(p)}, nil
+}
+
+// lastVersion returns true if the PR is the last version and has not been updated since.
+func lastVersion(v Version, p pullrequest.PullRequest) bool {
+	return v.PR == p.Number && v.Commit == p.HeadRef.OID && !p.UpdatedAt.After(v.UpdatedDate)
 }
 
-func newVersion(r CheckRequest, p pullrequest.PullRequest) bool {
+func newVersion(r CheckRequest, p pullrequest.PullRequest, since time.Time) bool {
 	switch {
 	// negative filters
-	case pullrequest.SkipCI(r.Source.DisableCISkip)(p),
-		pullrequest.BaseBranch(r.Source.BaseBranch)(p),
-		pullrequest.ApprovedReviewCount(r.Source.RequiredReviewApprovals)(p),
-		pullrequest.Labels(r.Source.Labels)(p),
-		pullrequest.Fork(r.Source.DisableForks)(p):
+	case excluded(r, p),
+		pullrequest.TerminalStatus(skipContexts(r.Source.SkipIfStatus))(p):
 		return false
 	// positive filters
-	case pullrequest.Created(r.Version.UpdatedDate)(p),
+	case pullrequest.Created(since)(p),
 		pullrequest.BaseRefChanged()(p),
 		pullrequest.BaseRefForcePushed()(p),
 		pullrequest.HeadRefForcePushed()(p),
 		pullrequest.Reopened()(p),
 		pullrequest.BuildCI()(p),
-		pullrequest.NewCommits(r.Version.UpdatedDate)(p):
+		pullrequest.NewCommits(since)(p):
 		return true
 	}
 
 	return false
 }
 
-func pullRequestFiles(n int, manager Github) ([]string, error) {
-	files, err := manager.GetChangedFiles(n)
+// excluded returns true if the PR is excluded by the configuration, regardless of its head commit.
+func excluded(r CheckRequest, p pullrequest.PullRequest) bool {
+	return pullrequest.SkipCI(r.Source.DisableCISkip)(p) ||
+		pullrequest.BaseBranch(r.Source.BaseBranch)(p) ||
+		pullrequest.ApprovedReviewCount(r.Source.RequiredReviewApprovals)(p) ||
+		pullrequest.Labels(r.Source.Labels)(p) ||
+		pullrequest.Fork(r.Source.DisableForks)(p)
+}
+
+// headChanged returns true if the PR was created, or its head was pushed or force pushed, since the last check.
+func headChanged(p pullrequest.PullRequest, since time.Time) bool {
+	return pullrequest.Created(since)(p) ||
+		pullrequest.NewCommits(since)(p) ||
+		pullrequest.HeadRefForcePushed()(p)
+}
+
+// skipContexts expands `skip_if_status` entries to the context naming used when setting a status,
+// an entry without a base context is prefixed with the default base context.
+func skipContexts(v []string) []string {
+	contexts := make([]string, 0, len(v))
+	for _, c := range v {
+		if !strings.Contains(c, "/") {
+			c = commitStatusContext("", c)
+		}
+		contexts = append(contexts, c)
+	}
+	return contexts
+}
+
+// changedFiles looks up the files of PRs which were not (completely) listed by the search,
+// using a bounded number of concurrent lookups. Results are stored in place, preserving order.
+func changedFiles(ctx context.Context, pulls []pullrequest.PullRequest, concurrency int, manager Github, cache *filesCache) error {
+	if concurrency < 1 {
+		concurrency = defaultChangedFilesConcurrency
+	}
+
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+		errs = make([]error, len(pulls))
+	)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pulls[i].Files, errs[i] = pullRequestFiles(ctx, pulls[i], manager, cache)
+			}
+		}()
+	}
+
+	for i, p := range pulls {
+		if p.FilesComplete {
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pullRequestFiles(ctx context.Context, p pullrequest.PullRequest, manager Github, cache *filesCache) ([]string, error) {
+	files, err := cachedChangedFiles(ctx, p.Number, p.HeadRef.OID, p.BaseRefOID, manager, cache)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list modified files: %s", err)
 	}
@@ -115,6 +343,25 @@ func pullRequestFiles(n int, manager Github) ([]string, error) {
 	return files, nil
 }
 
+// cachedChangedFiles returns the changed files of the PR at the head and base commit, consulting the cache first.
+func cachedChangedFiles(ctx context.Context, number int, head, base string, manager Github, cache *filesCache) ([]string, error) {
+	if files, ok := cache.get(number, head, base); ok {
+		log.Println("changed files cache hit:", number)
+		return files, nil
+	}
+
+	files, err := manager.GetChangedFiles(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.put(number, head, base, files); err != nil {
+		log.Println("failed to cache changed files:", err)
+	}
+
+	return files, nil
+}
+
 // CheckRequest ...
 type CheckRequest struct {
 	Source  Source  `json:"source"`
@@ -128,10 +375,40 @@ func (r CheckResponse) Len() int {
 	return len(r)
 }
 
+// Less orders versions by date, using the PR number and commit as tie-breakers for a stable order.
 func (r CheckResponse) Less(i, j int) bool {
-	return r[j].UpdatedDate.After(r[i].UpdatedDate)
+	if !r[i].UpdatedDate.Equal(r[j].UpdatedDate) {
+		return r[i].UpdatedDate.Before(r[j].UpdatedDate)
+	}
+	if r[i].PR != r[j].PR {
+		return r[i].PR < r[j].PR
+	}
+	return r[i].Commit < r[j].Commit
 }
 
 func (r CheckResponse) Swap(i, j int) {
 	r[i], r[j] = r[j], r[i]
 }
+
+// dedupe removes versions with the same PR number and commit from a sorted response,
+// keeping the most recently updated one.
+func (r CheckResponse) dedupe() CheckResponse {
+	type key struct {
+		pr     int
+		commit string
+	}
+
+	seen := make(map[key]bool, len(r))
+	response := make(CheckResponse, len(r))
+	n := len(r)
+	for i := len(r) - 1; i >= 0; i-- {
+		k := key{r[i].PR, r[i].Commit}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		n--
+		response[n] = r[i]
+	}
+	return response[n:]
+}
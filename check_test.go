@@ -1,9 +1,19 @@
 package resource_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
 	"testing"
+	"testing/quick"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	resource "github.com/telia-oss/github-pr-resource"
 	"github.com/telia-oss/github-pr-resource/fakes"
 	"github.com/telia-oss/github-pr-resource/pullrequest"
@@ -106,6 +116,42 @@ func TestCheck(t *testing.T) {
 				resource.NewVersion(testPullRequests[6]),
 			},
 		},
+		{
+			description: "check skips pull requests with a terminal status for a configured context",
+			source: resource.Source{
+				Repository:   "itsdalmo/test-repository",
+				AccessToken:  "oauthtoken",
+				SkipIfStatus: []string{"status"},
+			},
+			version: resource.Version{},
+			pullRequests: append(testPullRequests[:8:8],
+				withStatus(testPullRequests[8], "concourse-ci/status", "SUCCESS"),
+			),
+			files: [][]string{},
+			expected: resource.CheckResponse{
+				resource.NewVersion(testPullRequests[6]),
+			},
+		},
+		{
+			description: "check returns all versions if there is no previous and initial versions is all",
+			source: resource.Source{
+				Repository:      "itsdalmo/test-repository",
+				AccessToken:     "oauthtoken",
+				InitialVersions: resource.InitialVersionsAll,
+			},
+			version:      resource.Version{},
+			pullRequests: testPullRequests,
+			files:        [][]string{},
+			expected: resource.CheckResponse{
+				resource.NewVersion(testPullRequests[1]),
+				resource.NewVersion(testPullRequests[2]),
+				resource.NewVersion(testPullRequests[3]),
+				resource.NewVersion(testPullRequests[4]),
+				resource.NewVersion(testPullRequests[5]),
+				resource.NewVersion(testPullRequests[6]),
+				resource.NewVersion(testPullRequests[8]),
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -119,7 +165,7 @@ func TestCheck(t *testing.T) {
 			}
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			output, err := resource.Check(input, github)
+			output, err := resource.Check(context.Background(), input, github)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.expected, output)
@@ -128,3 +174,643 @@ func TestCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckInitialVersions(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		version     resource.Version
+		calls       int
+		since       time.Duration
+		expected    resource.CheckResponse
+	}{
+		{
+			description: "check searches three years back by default",
+			source: resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+			},
+			calls:    1,
+			since:    3 * 365 * 24 * time.Hour,
+			expected: resource.CheckResponse{resource.NewVersion(testPullRequests[8])},
+		},
+		{
+			description: "check searches within the initial lookback",
+			source: resource.Source{
+				Repository:      "itsdalmo/test-repository",
+				AccessToken:     "oauthtoken",
+				InitialLookback: resource.Duration(48 * time.Hour),
+			},
+			calls:    1,
+			since:    48 * time.Hour,
+			expected: resource.CheckResponse{resource.NewVersion(testPullRequests[8])},
+		},
+		{
+			description: "check only searches for recent updates if initial versions is none",
+			source: resource.Source{
+				Repository:      "itsdalmo/test-repository",
+				AccessToken:     "oauthtoken",
+				InitialVersions: resource.InitialVersionsNone,
+			},
+			calls:    1,
+			since:    10 * time.Minute,
+			expected: resource.CheckResponse{resource.NewVersion(testPullRequests[8])},
+		},
+		{
+			description: "check ignores the initial lookback if initial versions is none",
+			source: resource.Source{
+				Repository:      "itsdalmo/test-repository",
+				AccessToken:     "oauthtoken",
+				InitialLookback: resource.Duration(30 * 24 * time.Hour),
+				InitialVersions: resource.InitialVersionsNone,
+			},
+			calls:    1,
+			since:    10 * time.Minute,
+			expected: resource.CheckResponse{resource.NewVersion(testPullRequests[8])},
+		},
+		{
+			description: "check ignores initial versions none when there is a previous version",
+			source: resource.Source{
+				Repository:      "itsdalmo/test-repository",
+				AccessToken:     "oauthtoken",
+				InitialVersions: resource.InitialVersionsNone,
+			},
+			version: resource.NewVersion(testPullRequests[8]),
+			calls:   1,
+			expected: resource.CheckResponse{
+				resource.NewVersion(testPullRequests[2]),
+				resource.NewVersion(testPullRequests[3]),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.ListOpenPullRequestsReturns(testPullRequests, nil)
+
+			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
+			output, err := resource.Check(context.Background(), input, github)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			if assert.Equal(t, tc.calls, github.ListOpenPullRequestsCallCount()) && tc.since > 0 {
+				_, since := github.ListOpenPullRequestsArgsForCall(0)
+				assert.WithinDuration(t, time.Now().Add(-tc.since), since, 2*24*time.Hour)
+			}
+		})
+	}
+}
+
+func TestCheckInitialVersionsNone(t *testing.T) {
+	now := time.Now()
+	existing := pullrequest.PullRequest{
+		Number:    1,
+		CreatedAt: now.Add(-72 * time.Hour),
+		UpdatedAt: now.Add(-48 * time.Hour),
+		HeadRef:   pullrequest.Commit{OID: "oid1", CommittedDate: now.Add(-48 * time.Hour)},
+	}
+	pushed := existing
+	pushed.UpdatedAt = now.Add(-time.Minute)
+	pushed.HeadRef = pullrequest.Commit{OID: "oid2", CommittedDate: now.Add(-time.Minute)}
+
+	source := resource.Source{
+		Repository:      "itsdalmo/test-repository",
+		AccessToken:     "oauthtoken",
+		InitialVersions: resource.InitialVersionsNone,
+	}
+
+	// The first check skips the existing pull request
+	github := new(fakes.FakeGithub)
+	github.ListOpenPullRequestsReturns([]pullrequest.PullRequest{existing}, nil)
+
+	output, err := resource.Check(context.Background(), resource.CheckRequest{Source: source}, github)
+	require.NoError(t, err)
+	assert.Equal(t, resource.CheckResponse{}, output)
+
+	// The next check (still without a version) returns the push to it
+	github.ListOpenPullRequestsReturns([]pullrequest.PullRequest{pushed}, nil)
+
+	output, err = resource.Check(context.Background(), resource.CheckRequest{Source: source}, github)
+	require.NoError(t, err)
+	assert.Equal(t, resource.CheckResponse{resource.NewVersion(pushed)}, output)
+
+	// After which checks continue from that version
+	output, err = resource.Check(context.Background(), resource.CheckRequest{Source: source, Version: output[0]}, github)
+	require.NoError(t, err)
+	assert.Equal(t, resource.CheckResponse{resource.NewVersion(pushed)}, output)
+}
+
+func TestCheckPullRequestNumber(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		pullRequest pullrequest.PullRequest
+		expected    resource.CheckResponse
+		err         bool
+	}{
+		{
+			description: "check resolves a version with only a pull request number to its head",
+			source: resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+			},
+			pullRequest: testPullRequests[1],
+			expected: resource.CheckResponse{
+				resource.NewVersion(testPullRequests[1]),
+			},
+		},
+		{
+			description: "check bypasses time based filters for a version with only a pull request number",
+			source: resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+			},
+			pullRequest: testPullRequests[0],
+			expected: resource.CheckResponse{
+				resource.NewVersion(testPullRequests[0]),
+			},
+		},
+		{
+			description: "check does not resolve a pull request from a fork when forks are disabled",
+			source: resource.Source{
+				Repository:   "itsdalmo/test-repository",
+				AccessToken:  "oauthtoken",
+				DisableForks: true,
+			},
+			pullRequest: createTestPR(10, "master", false, true, false, false, 0, nil),
+			err:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.GetPullRequestReturns(tc.pullRequest, nil)
+
+			input := resource.CheckRequest{Source: tc.source, Version: resource.Version{PR: tc.pullRequest.Number}}
+			output, err := resource.Check(context.Background(), input, github)
+
+			if tc.err {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			assert.Equal(t, 0, github.ListOpenPullRequestsCallCount())
+			if assert.Equal(t, 1, github.GetPullRequestCallCount()) {
+				_, pr, commit := github.GetPullRequestArgsForCall(0)
+				assert.Equal(t, tc.pullRequest.Number, pr)
+				assert.Equal(t, "", commit)
+			}
+		})
+	}
+}
+
+func TestCheckChangedFiles(t *testing.T) {
+	var pulls []pullrequest.PullRequest
+	for i := 1; i <= 20; i++ {
+		pulls = append(pulls, createTestPR(i, "master", false, false, false, false, 0, nil))
+	}
+	// the files of the first PR are completely listed by the search
+	pulls[0].Files = []string{"docs/README.md"}
+	pulls[0].FilesComplete = true
+
+	github := new(fakes.FakeGithub)
+	github.ListOpenPullRequestsReturns(pulls, nil)
+	github.GetChangedFilesStub = func(_ context.Context, n int) ([]string, error) {
+		if n%2 == 0 {
+			return []string{"docs/README.md"}, nil
+		}
+		return []string{"main.go"}, nil
+	}
+
+	input := resource.CheckRequest{
+		Source: resource.Source{
+			Repository:              "itsdalmo/test-repository",
+			AccessToken:             "oauthtoken",
+			Paths:                   []string{"docs/*"},
+			ChangedFilesConcurrency: 3,
+		},
+		Version: resource.Version{PR: 100, Commit: "oid100", UpdatedDate: time.Now().AddDate(0, 0, -1)},
+	}
+	output, err := resource.Check(context.Background(), input, github)
+
+	if assert.NoError(t, err) {
+		var expected resource.CheckResponse
+		for _, p := range pulls {
+			if p.Number == 1 || p.Number%2 == 0 {
+				expected = append(expected, resource.NewVersion(p))
+			}
+		}
+		assert.Equal(t, expected, output)
+	}
+	assert.Equal(t, 19, github.GetChangedFilesCallCount())
+}
+
+func TestCheckChangedFilesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "changed-files-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pulls := []pullrequest.PullRequest{
+		createTestPR(1, "master", false, false, false, false, 0, nil),
+		createTestPR(2, "master", false, false, false, false, 0, nil),
+	}
+
+	github := new(fakes.FakeGithub)
+	github.ListOpenPullRequestsReturns(pulls, nil)
+	github.GetChangedFilesReturns([]string{"docs/README.md"}, nil)
+
+	input := resource.CheckRequest{
+		Source: resource.Source{
+			Repository:            "itsdalmo/test-repository",
+			AccessToken:           "oauthtoken",
+			Paths:                 []string{"docs/*"},
+			ChangedFilesCacheDir:  dir,
+			ChangedFilesCacheSize: 2,
+		},
+		Version: resource.Version{PR: 100, Commit: "oid100", UpdatedDate: time.Now().AddDate(0, 0, -1)},
+	}
+
+	for i := 0; i < 2; i++ {
+		output, err := resource.Check(context.Background(), input, github)
+		require.NoError(t, err)
+		assert.Len(t, output, 2)
+	}
+	assert.Equal(t, 2, github.GetChangedFilesCallCount(), "second check should use the cache")
+
+	// a new head commit is not cached & evicts the least recently used entry
+	pulls[0].HeadRef.OID = "oid1-new"
+	github.ListOpenPullRequestsReturns(pulls, nil)
+
+	_, err = resource.Check(context.Background(), input, github)
+	require.NoError(t, err)
+	assert.Equal(t, 3, github.GetChangedFilesCallCount())
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestCheckSkipUnaffectedProjects(t *testing.T) {
+	pulls := []pullrequest.PullRequest{
+		createTestPR(1, "master", false, false, false, false, 0, nil),
+		createTestPR(2, "master", false, false, false, false, 0, nil),
+	}
+	pulls[0].Files, pulls[0].FilesComplete = []string{"README.md"}, true
+	pulls[1].Files, pulls[1].FilesComplete = []string{"lib/util.go"}, true
+
+	github := new(fakes.FakeGithub)
+	github.ListOpenPullRequestsReturns(pulls, nil)
+
+	input := resource.CheckRequest{
+		Source: resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+			Projects: []resource.Project{
+				{Name: "api", Paths: []string{"api/*"}, DependsOn: []string{"lib"}},
+				{Name: "lib", Paths: []string{"lib/*"}},
+			},
+			SkipUnaffectedProjects: true,
+		},
+		Version: resource.Version{PR: 100, Commit: "oid100", UpdatedDate: time.Now().AddDate(0, 0, -1)},
+	}
+	output, err := resource.Check(context.Background(), input, github)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, resource.CheckResponse{resource.NewVersion(pulls[1])}, output)
+	}
+	assert.Equal(t, 0, github.GetChangedFilesCallCount())
+}
+
+func TestCheckStackedPullRequests(t *testing.T) {
+	parent := createTestPR(2, "master", false, false, false, false, 0, nil)
+	stacked := createTestPR(1, "pr2", false, false, false, false, 0, nil)
+
+	// a comment requesting a build, without a change of the head
+	commented := parent
+	commented.Comments = []pullrequest.Comment{{CreatedAt: parent.UpdatedAt, Body: "[build ci]"}}
+
+	tests := []struct {
+		description string
+		enabled     bool
+		pull        pullrequest.PullRequest
+		since       time.Time
+		expected    resource.CheckResponse
+		lookups     int
+	}{
+		{
+			description: "stacked pull requests follow the pull request they are stacked on",
+			enabled:     true,
+			pull:        parent,
+			since:       time.Now(),
+			expected: resource.CheckResponse{
+				resource.NewVersion(parent),
+				resource.Version{PR: 1, Commit: "oid1", UpdatedDate: parent.UpdatedAt},
+			},
+			lookups: 1,
+		},
+		{
+			description: "stacked pull requests are not looked up if the head did not change",
+			enabled:     true,
+			pull:        commented,
+			since:       parent.UpdatedAt.Add(time.Hour),
+			expected:    resource.CheckResponse{resource.NewVersion(commented)},
+			lookups:     0,
+		},
+		{
+			description: "stacked pull requests are not looked up unless enabled",
+			pull:        parent,
+			since:       time.Now(),
+			expected:    resource.CheckResponse{resource.NewVersion(parent)},
+			lookups:     0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.ListOpenPullRequestsReturns([]pullrequest.PullRequest{tc.pull}, nil)
+			github.ListOpenPullRequestsByRefStub = func(_ context.Context, base, head string) ([]pullrequest.PullRequest, error) {
+				if base == "pr2" {
+					return []pullrequest.PullRequest{stacked}, nil
+				}
+				return nil, nil
+			}
+
+			input := resource.CheckRequest{
+				Source: resource.Source{
+					Repository:          "itsdalmo/test-repository",
+					AccessToken:         "oauthtoken",
+					StackedPullRequests: tc.enabled,
+				},
+				Version: resource.Version{PR: 100, Commit: "oid100", UpdatedDate: tc.since},
+			}
+			output, err := resource.Check(context.Background(), input, github)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			assert.Equal(t, tc.lookups, github.ListOpenPullRequestsByRefCallCount())
+		})
+	}
+}
+
+func TestCheckMergeQueue(t *testing.T) {
+	last := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	group := func(number int, base string, created time.Time) pullrequest.MergeGroup {
+		return pullrequest.MergeGroup{
+			Ref:         fmt.Sprintf("gh-readonly-queue/%s/pr-%d-sha", base, number),
+			Number:      number,
+			BaseRefName: base,
+			HeadRef:     pullrequest.Commit{OID: fmt.Sprintf("group%d", number), CommittedDate: created},
+		}
+	}
+	version := func(g pullrequest.MergeGroup) resource.Version {
+		return resource.Version{PR: g.Number, Commit: g.HeadRef.OID, UpdatedDate: g.HeadRef.CommittedDate, MergeGroup: g.Ref}
+	}
+
+	groups := []pullrequest.MergeGroup{
+		group(3, "master", last.Add(2*time.Minute)),
+		group(1, "master", last),
+		group(2, "master", last.Add(time.Minute)),
+		group(4, "release", last.Add(time.Minute)),
+		group(5, "master", last.Add(-time.Minute)),
+	}
+
+	tests := []struct {
+		description string
+		version     resource.Version
+		expected    resource.CheckResponse
+	}{
+		{
+			description: "returns the latest merge group without a previous version",
+			expected:    resource.CheckResponse{version(groups[0])},
+		},
+		{
+			description: "returns merge groups created since the previous version, in order",
+			version:     version(groups[1]),
+			expected:    resource.CheckResponse{version(groups[2]), version(groups[0])},
+		},
+		{
+			description: "returns the previous version without new merge groups",
+			version:     version(groups[0]),
+			expected:    resource.CheckResponse{version(groups[0])},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.ListMergeGroupsReturns(groups, nil)
+
+			input := resource.CheckRequest{
+				Source: resource.Source{
+					Repository:  "itsdalmo/test-repository",
+					AccessToken: "oauthtoken",
+					MergeQueue:  true,
+					BaseBranch:  "master",
+				},
+				Version: tc.version,
+			}
+			output, err := resource.Check(context.Background(), input, github)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			assert.Equal(t, 0, github.ListOpenPullRequestsCallCount())
+		})
+	}
+}
+
+func TestCheckSearchOverlap(t *testing.T) {
+	last := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	pull := func(number int, oid string, updated time.Time) pullrequest.PullRequest {
+		return pullrequest.PullRequest{
+			Number:    number,
+			CreatedAt: last.AddDate(0, 0, -1),
+			UpdatedAt: updated,
+			HeadRef:   pullrequest.Commit{OID: oid, CommittedDate: updated},
+		}
+	}
+
+	// the last version, a PR updated just before it, which was missing from the search index at the last check,
+	// & a PR updated after it
+	version := resource.NewVersion(pull(1, "oid1", last))
+	missed := pull(2, "oid2", last.Add(-30*time.Second))
+	updated := pull(3, "oid3", last.Add(30*time.Second))
+
+	tests := []struct {
+		description string
+		overlap     time.Duration
+		pulls       []pullrequest.PullRequest
+		expected    resource.CheckResponse
+	}{
+		{
+			description: "check without overlap does not return updates before the last version",
+			pulls:       []pullrequest.PullRequest{pull(1, "oid1", last), missed},
+			expected:    resource.CheckResponse{version},
+		},
+		{
+			description: "check with overlap returns missed updates before the last version",
+			overlap:     time.Minute,
+			pulls:       []pullrequest.PullRequest{pull(1, "oid1", last), missed},
+			expected:    resource.CheckResponse{resource.NewVersion(missed), version},
+		},
+		{
+			description: "check with overlap returns updates after the last version last",
+			overlap:     time.Minute,
+			pulls:       []pullrequest.PullRequest{pull(1, "oid1", last), missed, updated},
+			expected:    resource.CheckResponse{resource.NewVersion(missed), version, resource.NewVersion(updated)},
+		},
+		{
+			description: "check with overlap does not return the last version without missed updates",
+			overlap:     time.Minute,
+			pulls:       []pullrequest.PullRequest{pull(1, "oid1", last), updated},
+			expected:    resource.CheckResponse{resource.NewVersion(updated)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.ListOpenPullRequestsReturns(tc.pulls, nil)
+
+			input := resource.CheckRequest{
+				Source: resource.Source{
+					Repository:    "itsdalmo/test-repository",
+					AccessToken:   "oauthtoken",
+					SearchOverlap: resource.Duration(tc.overlap),
+				},
+				Version: version,
+			}
+			output, err := resource.Check(context.Background(), input, github)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, output)
+			}
+			if assert.Equal(t, 1, github.ListOpenPullRequestsCallCount()) {
+				_, since := github.ListOpenPullRequestsArgsForCall(0)
+				assert.Equal(t, last.Add(-tc.overlap), since)
+			}
+		})
+	}
+}
+
+func TestCheckSearchOverlapConsecutiveChecks(t *testing.T) {
+	last := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	pull := func(number int, oid string, updated time.Time) pullrequest.PullRequest {
+		return pullrequest.PullRequest{
+			Number:    number,
+			CreatedAt: last.AddDate(0, 0, -1),
+			UpdatedAt: updated,
+			HeadRef:   pullrequest.Commit{OID: oid, CommittedDate: updated},
+		}
+	}
+	first := pull(1, "oid1", last.Add(-30*time.Second))
+	second := pull(2, "oid2", last)
+
+	github := new(fakes.FakeGithub)
+	github.ListOpenPullRequestsReturns([]pullrequest.PullRequest{first, second}, nil)
+
+	// both PRs stay in the search overlap, so every check finds them
+	input := resource.CheckRequest{
+		Source: resource.Source{
+			Repository:    "itsdalmo/test-repository",
+			AccessToken:   "oauthtoken",
+			SearchOverlap: resource.Duration(time.Minute),
+		},
+		Version: resource.Version{PR: 3, Commit: "oid3", UpdatedDate: last.Add(-time.Minute)},
+	}
+	output, err := resource.Check(context.Background(), input, github)
+	require.NoError(t, err)
+	assert.Equal(t, resource.CheckResponse{resource.NewVersion(first), resource.NewVersion(second)}, output)
+
+	// the first PR is returned again, but before the last version, which stays the latest
+	for i := 0; i < 2; i++ {
+		input.Version = output[len(output)-1]
+		output, err = resource.Check(context.Background(), input, github)
+		require.NoError(t, err)
+		assert.Equal(t, resource.CheckResponse{resource.NewVersion(first), resource.NewVersion(second)}, output)
+	}
+}
+
+func TestCheckOrdering(t *testing.T) {
+	version := resource.Version{PR: 1000, Commit: "previous", UpdatedDate: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	check := func(pulls []pullrequest.PullRequest) (resource.CheckResponse, []byte) {
+		github := new(fakes.FakeGithub)
+		github.ListOpenPullRequestsReturns(pulls, nil)
+
+		input := resource.CheckRequest{
+			Source:  resource.Source{Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken"},
+			Version: version,
+		}
+		output, err := resource.Check(context.Background(), input, github)
+		require.NoError(t, err)
+
+		b, err := json.Marshal(output)
+		require.NoError(t, err)
+		return output, b
+	}
+
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		pulls := randomPullRequests(r, version.UpdatedDate)
+
+		output, b := check(pulls)
+
+		// re-running over the same data in any order returns identical output
+		shuffled := append([]pullrequest.PullRequest{}, pulls...)
+		r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		if _, bb := check(shuffled); !bytes.Equal(b, bb) {
+			t.Logf("output differs for shuffled input:\n%s\n%s", b, bb)
+			return false
+		}
+
+		seen := make(map[string]bool)
+		for i, v := range output {
+			key := fmt.Sprintf("%d/%s", v.PR, v.Commit)
+			if seen[key] {
+				t.Logf("duplicate version: %s", key)
+				return false
+			}
+			seen[key] = true
+
+			if i > 0 && !output.Less(i-1, i) {
+				t.Logf("versions out of order: %v, %v", output[i-1], v)
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// randomPullRequests generates updates for a small set of PRs & commits on few distinct dates,
+// to provoke ties and duplicates (e.g. from overlapping search pages).
+func randomPullRequests(r *rand.Rand, since time.Time) []pullrequest.PullRequest {
+	pulls := make([]pullrequest.PullRequest, r.Intn(30))
+	for i := range pulls {
+		updated := since.Add(time.Duration(1+r.Intn(3)) * time.Second)
+		pulls[i] = pullrequest.PullRequest{
+			Number:    1 + r.Intn(5),
+			CreatedAt: since.AddDate(0, 0, -1),
+			UpdatedAt: updated,
+			HeadRef: pullrequest.Commit{
+				OID:           fmt.Sprintf("oid%d", r.Intn(3)),
+				CommittedDate: updated,
+			},
+		}
+	}
+	return pulls
+}
+
+func withStatus(p pullrequest.PullRequest, context, state string) pullrequest.PullRequest {
+	p.HeadRef.Statuses = append(p.HeadRef.Statuses, pullrequest.Status{Context: context, State: state})
+	return p
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	resource "github.com/telia-oss/github-pr-resource"
 	rlog "github.com/telia-oss/github-pr-resource/log"
@@ -20,6 +22,9 @@ func main() {
 	input := rlog.WriteStdin()
 	defer rlog.Close()
 
+	ctx, cancel := resource.SignalContext()
+	defer cancel()
+
 	if err := json.Unmarshal(input, &request); err != nil {
 		log.Fatalf("failed to unmarshal request: %s", err)
 	}
@@ -27,11 +32,21 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
+	github, err := resource.NewClient(&request.Source)
 	if err != nil {
 		log.Fatalf("failed to create github manager: %s", err)
 	}
-	response, err := resource.Check(request, github)
+	response, err := resource.Check(ctx, request, github)
+
+	// Report the GraphQL rate limit in the check output
+	if github, ok := github.(*resource.GithubClient); ok {
+		fmt.Fprintf(os.Stderr, "graphql rate limit: cost=%d remaining=%d reset=%s\n",
+			github.Cost, github.RateLimit.Remaining, github.RateLimit.ResetAt.Format(time.RFC3339))
+		if github.Skipped > 0 {
+			fmt.Fprintf(os.Stderr, "skipped %d malformed pull requests\n", github.Skipped)
+		}
+	}
+
 	if err != nil {
 		log.Fatalf("check failed: %s", err)
 	}
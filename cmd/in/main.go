@@ -16,6 +16,9 @@ func main() {
 	input := rlog.WriteStdin()
 	defer rlog.Close()
 
+	ctx, cancel := resource.SignalContext()
+	defer cancel()
+
 	if err := json.Unmarshal(input, &request); err != nil {
 		log.Fatalf("failed to unmarshal request: %s", err)
 	}
@@ -27,15 +30,21 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
+	github, err := resource.NewClient(&request.Source)
+	if err != nil {
+		log.Fatalf("failed to create github manager: %s", err)
+	}
 	git, err := resource.NewGitClient(&request.Source, outputDir, os.Stderr)
 	if err != nil {
 		log.Fatalf("failed to create git client: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
-	if err != nil {
-		log.Fatalf("failed to create github manager: %s", err)
+	response, err := resource.Get(ctx, request, github, git, outputDir)
+
+	// Clean up before log.Fatalf, which skips deferred calls
+	if err := git.Close(); err != nil {
+		log.Println("failed to close git client:", err)
 	}
-	response, err := resource.Get(request, github, git, outputDir)
+
 	if err != nil {
 		fmt.Println(err)
 		log.Fatalf("get failed: %s", err)
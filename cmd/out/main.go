@@ -15,6 +15,9 @@ func main() {
 	input := rlog.WriteStdin()
 	defer rlog.Close()
 
+	ctx, cancel := resource.SignalContext()
+	defer cancel()
+
 	if err := json.Unmarshal(input, &request); err != nil {
 		log.Fatalf("failed to unmarshal request: %s", err)
 	}
@@ -26,11 +29,11 @@ func main() {
 	if err := request.Source.Validate(); err != nil {
 		log.Fatalf("invalid source configuration: %s", err)
 	}
-	github, err := resource.NewGithubClient(&request.Source)
+	github, err := resource.NewClient(&request.Source)
 	if err != nil {
 		log.Fatalf("failed to create github manager: %s", err)
 	}
-	response, err := resource.Put(request, github, sourceDir)
+	response, err := resource.Put(ctx, request, github, sourceDir)
 	if err != nil {
 		log.Fatalf("put failed: %s", err)
 	}
@@ -3,6 +3,7 @@
 package resource_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -88,7 +89,7 @@ func TestComponentCheck(t *testing.T) {
 			require.NoError(t, err)
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			output, err := resource.Check(input, github)
+			output, err := resource.Check(context.Background(), input, github)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.expected, output)
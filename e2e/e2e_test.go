@@ -3,6 +3,7 @@
 package e2e_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -180,7 +181,7 @@ func TestCheckE2E(t *testing.T) {
 			require.NoError(t, err)
 
 			input := resource.CheckRequest{Source: tc.source, Version: tc.version}
-			output, err := resource.Check(input, github)
+			output, err := resource.Check(context.Background(), input, github)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.expected, output)
@@ -392,7 +393,7 @@ func TestGetAndPutE2E(t *testing.T) {
 
 			// Get (output and files)
 			getRequest := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.getParameters}
-			getOutput, err := resource.Get(getRequest, github, git, dir)
+			getOutput, err := resource.Get(context.Background(), getRequest, github, git, dir)
 
 			require.NoError(t, err)
 			assert.Equal(t, tc.version, getOutput.Version)
@@ -427,7 +428,7 @@ func TestGetAndPutE2E(t *testing.T) {
 
 			// Put
 			putRequest := resource.PutRequest{Source: tc.source, Params: tc.putParameters}
-			putOutput, err := resource.Put(putRequest, github, dir)
+			putOutput, err := resource.Put(context.Background(), putRequest, github, dir)
 
 			require.NoError(t, err)
 			assert.Equal(t, tc.version, putOutput.Version)
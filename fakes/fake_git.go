@@ -2,17 +2,34 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	resource "github.com/telia-oss/github-pr-resource"
 )
 
 type FakeGit struct {
-	CheckoutStub        func(string, string) error
+	ChangedFilesStub        func(context.Context, string, string) ([]string, error)
+	changedFilesMutex       sync.RWMutex
+	changedFilesArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	changedFilesReturns struct {
+		result1 []string
+		result2 error
+	}
+	changedFilesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	CheckoutStub        func(context.Context, string, string) error
 	checkoutMutex       sync.RWMutex
 	checkoutArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	checkoutReturns struct {
 		result1 error
@@ -20,12 +37,13 @@ type FakeGit struct {
 	checkoutReturnsOnCall map[int]struct {
 		result1 error
 	}
-	CloneStub        func(string, string, int) error
+	CloneStub        func(context.Context, string, string, int) error
 	cloneMutex       sync.RWMutex
 	cloneArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
+		arg3 string
+		arg4 int
 	}
 	cloneReturns struct {
 		result1 error
@@ -33,11 +51,12 @@ type FakeGit struct {
 	cloneReturnsOnCall map[int]struct {
 		result1 error
 	}
-	FetchStub        func(int, int) error
+	FetchStub        func(context.Context, int, int) error
 	fetchMutex       sync.RWMutex
 	fetchArgsForCall []struct {
-		arg1 int
+		arg1 context.Context
 		arg2 int
+		arg3 int
 	}
 	fetchReturns struct {
 		result1 error
@@ -45,10 +64,37 @@ type FakeGit struct {
 	fetchReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GitCryptUnlockStub        func(string) error
+	FetchCommitStub        func(context.Context, string, int) error
+	fetchCommitMutex       sync.RWMutex
+	fetchCommitArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}
+	fetchCommitReturns struct {
+		result1 error
+	}
+	fetchCommitReturnsOnCall map[int]struct {
+		result1 error
+	}
+	FetchRefStub        func(context.Context, string, int) error
+	fetchRefMutex       sync.RWMutex
+	fetchRefArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}
+	fetchRefReturns struct {
+		result1 error
+	}
+	fetchRefReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GitCryptUnlockStub        func(context.Context, string) error
 	gitCryptUnlockMutex       sync.RWMutex
 	gitCryptUnlockArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	gitCryptUnlockReturns struct {
 		result1 error
@@ -56,10 +102,11 @@ type FakeGit struct {
 	gitCryptUnlockReturnsOnCall map[int]struct {
 		result1 error
 	}
-	InitStub        func(string) error
+	InitStub        func(context.Context, string) error
 	initMutex       sync.RWMutex
 	initArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	initReturns struct {
 		result1 error
@@ -67,10 +114,11 @@ type FakeGit struct {
 	initReturnsOnCall map[int]struct {
 		result1 error
 	}
-	MergeStub        func(string) error
+	MergeStub        func(context.Context, string) error
 	mergeMutex       sync.RWMutex
 	mergeArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	mergeReturns struct {
 		result1 error
@@ -78,12 +126,28 @@ type FakeGit struct {
 	mergeReturnsOnCall map[int]struct {
 		result1 error
 	}
-	PullStub        func(string, string, int) error
+	MergeBaseStub        func(context.Context, string, string) (string, error)
+	mergeBaseMutex       sync.RWMutex
+	mergeBaseArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	mergeBaseReturns struct {
+		result1 string
+		result2 error
+	}
+	mergeBaseReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	PullStub        func(context.Context, string, string, int) error
 	pullMutex       sync.RWMutex
 	pullArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
+		arg3 string
+		arg4 int
 	}
 	pullReturns struct {
 		result1 error
@@ -91,11 +155,12 @@ type FakeGit struct {
 	pullReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RebaseStub        func(string, string) error
+	RebaseStub        func(context.Context, string, string) error
 	rebaseMutex       sync.RWMutex
 	rebaseArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
+		arg3 string
 	}
 	rebaseReturns struct {
 		result1 error
@@ -103,10 +168,11 @@ type FakeGit struct {
 	rebaseReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RevParseStub        func(string) (string, error)
+	RevParseStub        func(context.Context, string) (string, error)
 	revParseMutex       sync.RWMutex
 	revParseArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	revParseReturns struct {
 		result1 string
@@ -120,17 +186,83 @@ type FakeGit struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGit) Checkout(arg1 string, arg2 string) error {
+func (fake *FakeGit) ChangedFiles(arg1 context.Context, arg2 string, arg3 string) ([]string, error) {
+	fake.changedFilesMutex.Lock()
+	ret, specificReturn := fake.changedFilesReturnsOnCall[len(fake.changedFilesArgsForCall)]
+	fake.changedFilesArgsForCall = append(fake.changedFilesArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("ChangedFiles", []interface{}{arg1, arg2, arg3})
+	fake.changedFilesMutex.Unlock()
+	if fake.ChangedFilesStub != nil {
+		return fake.ChangedFilesStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.changedFilesReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGit) ChangedFilesCallCount() int {
+	fake.changedFilesMutex.RLock()
+	defer fake.changedFilesMutex.RUnlock()
+	return len(fake.changedFilesArgsForCall)
+}
+
+func (fake *FakeGit) ChangedFilesCalls(stub func(context.Context, string, string) ([]string, error)) {
+	fake.changedFilesMutex.Lock()
+	defer fake.changedFilesMutex.Unlock()
+	fake.ChangedFilesStub = stub
+}
+
+func (fake *FakeGit) ChangedFilesArgsForCall(i int) (context.Context, string, string) {
+	fake.changedFilesMutex.RLock()
+	defer fake.changedFilesMutex.RUnlock()
+	argsForCall := fake.changedFilesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGit) ChangedFilesReturns(result1 []string, result2 error) {
+	fake.changedFilesMutex.Lock()
+	defer fake.changedFilesMutex.Unlock()
+	fake.ChangedFilesStub = nil
+	fake.changedFilesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) ChangedFilesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.changedFilesMutex.Lock()
+	defer fake.changedFilesMutex.Unlock()
+	fake.ChangedFilesStub = nil
+	if fake.changedFilesReturnsOnCall == nil {
+		fake.changedFilesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.changedFilesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) Checkout(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.checkoutMutex.Lock()
 	ret, specificReturn := fake.checkoutReturnsOnCall[len(fake.checkoutArgsForCall)]
 	fake.checkoutArgsForCall = append(fake.checkoutArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("Checkout", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("Checkout", []interface{}{arg1, arg2, arg3})
 	fake.checkoutMutex.Unlock()
 	if fake.CheckoutStub != nil {
-		return fake.CheckoutStub(arg1, arg2)
+		return fake.CheckoutStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -145,17 +277,17 @@ func (fake *FakeGit) CheckoutCallCount() int {
 	return len(fake.checkoutArgsForCall)
 }
 
-func (fake *FakeGit) CheckoutCalls(stub func(string, string) error) {
+func (fake *FakeGit) CheckoutCalls(stub func(context.Context, string, string) error) {
 	fake.checkoutMutex.Lock()
 	defer fake.checkoutMutex.Unlock()
 	fake.CheckoutStub = stub
 }
 
-func (fake *FakeGit) CheckoutArgsForCall(i int) (string, string) {
+func (fake *FakeGit) CheckoutArgsForCall(i int) (context.Context, string, string) {
 	fake.checkoutMutex.RLock()
 	defer fake.checkoutMutex.RUnlock()
 	argsForCall := fake.checkoutArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGit) CheckoutReturns(result1 error) {
@@ -181,18 +313,19 @@ func (fake *FakeGit) CheckoutReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Clone(arg1 string, arg2 string, arg3 int) error {
+func (fake *FakeGit) Clone(arg1 context.Context, arg2 string, arg3 string, arg4 int) error {
 	fake.cloneMutex.Lock()
 	ret, specificReturn := fake.cloneReturnsOnCall[len(fake.cloneArgsForCall)]
 	fake.cloneArgsForCall = append(fake.cloneArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("Clone", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Clone", []interface{}{arg1, arg2, arg3, arg4})
 	fake.cloneMutex.Unlock()
 	if fake.CloneStub != nil {
-		return fake.CloneStub(arg1, arg2, arg3)
+		return fake.CloneStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -207,17 +340,17 @@ func (fake *FakeGit) CloneCallCount() int {
 	return len(fake.cloneArgsForCall)
 }
 
-func (fake *FakeGit) CloneCalls(stub func(string, string, int) error) {
+func (fake *FakeGit) CloneCalls(stub func(context.Context, string, string, int) error) {
 	fake.cloneMutex.Lock()
 	defer fake.cloneMutex.Unlock()
 	fake.CloneStub = stub
 }
 
-func (fake *FakeGit) CloneArgsForCall(i int) (string, string, int) {
+func (fake *FakeGit) CloneArgsForCall(i int) (context.Context, string, string, int) {
 	fake.cloneMutex.RLock()
 	defer fake.cloneMutex.RUnlock()
 	argsForCall := fake.cloneArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeGit) CloneReturns(result1 error) {
@@ -243,17 +376,18 @@ func (fake *FakeGit) CloneReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Fetch(arg1 int, arg2 int) error {
+func (fake *FakeGit) Fetch(arg1 context.Context, arg2 int, arg3 int) error {
 	fake.fetchMutex.Lock()
 	ret, specificReturn := fake.fetchReturnsOnCall[len(fake.fetchArgsForCall)]
 	fake.fetchArgsForCall = append(fake.fetchArgsForCall, struct {
-		arg1 int
+		arg1 context.Context
 		arg2 int
-	}{arg1, arg2})
-	fake.recordInvocation("Fetch", []interface{}{arg1, arg2})
+		arg3 int
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("Fetch", []interface{}{arg1, arg2, arg3})
 	fake.fetchMutex.Unlock()
 	if fake.FetchStub != nil {
-		return fake.FetchStub(arg1, arg2)
+		return fake.FetchStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -268,17 +402,17 @@ func (fake *FakeGit) FetchCallCount() int {
 	return len(fake.fetchArgsForCall)
 }
 
-func (fake *FakeGit) FetchCalls(stub func(int, int) error) {
+func (fake *FakeGit) FetchCalls(stub func(context.Context, int, int) error) {
 	fake.fetchMutex.Lock()
 	defer fake.fetchMutex.Unlock()
 	fake.FetchStub = stub
 }
 
-func (fake *FakeGit) FetchArgsForCall(i int) (int, int) {
+func (fake *FakeGit) FetchArgsForCall(i int) (context.Context, int, int) {
 	fake.fetchMutex.RLock()
 	defer fake.fetchMutex.RUnlock()
 	argsForCall := fake.fetchArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGit) FetchReturns(result1 error) {
@@ -304,16 +438,141 @@ func (fake *FakeGit) FetchReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) GitCryptUnlock(arg1 string) error {
+func (fake *FakeGit) FetchCommit(arg1 context.Context, arg2 string, arg3 int) error {
+	fake.fetchCommitMutex.Lock()
+	ret, specificReturn := fake.fetchCommitReturnsOnCall[len(fake.fetchCommitArgsForCall)]
+	fake.fetchCommitArgsForCall = append(fake.fetchCommitArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("FetchCommit", []interface{}{arg1, arg2, arg3})
+	fake.fetchCommitMutex.Unlock()
+	if fake.FetchCommitStub != nil {
+		return fake.FetchCommitStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.fetchCommitReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeGit) FetchCommitCallCount() int {
+	fake.fetchCommitMutex.RLock()
+	defer fake.fetchCommitMutex.RUnlock()
+	return len(fake.fetchCommitArgsForCall)
+}
+
+func (fake *FakeGit) FetchCommitCalls(stub func(context.Context, string, int) error) {
+	fake.fetchCommitMutex.Lock()
+	defer fake.fetchCommitMutex.Unlock()
+	fake.FetchCommitStub = stub
+}
+
+func (fake *FakeGit) FetchCommitArgsForCall(i int) (context.Context, string, int) {
+	fake.fetchCommitMutex.RLock()
+	defer fake.fetchCommitMutex.RUnlock()
+	argsForCall := fake.fetchCommitArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGit) FetchCommitReturns(result1 error) {
+	fake.fetchCommitMutex.Lock()
+	defer fake.fetchCommitMutex.Unlock()
+	fake.FetchCommitStub = nil
+	fake.fetchCommitReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchCommitReturnsOnCall(i int, result1 error) {
+	fake.fetchCommitMutex.Lock()
+	defer fake.fetchCommitMutex.Unlock()
+	fake.FetchCommitStub = nil
+	if fake.fetchCommitReturnsOnCall == nil {
+		fake.fetchCommitReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.fetchCommitReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchRef(arg1 context.Context, arg2 string, arg3 int) error {
+	fake.fetchRefMutex.Lock()
+	ret, specificReturn := fake.fetchRefReturnsOnCall[len(fake.fetchRefArgsForCall)]
+	fake.fetchRefArgsForCall = append(fake.fetchRefArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("FetchRef", []interface{}{arg1, arg2, arg3})
+	fake.fetchRefMutex.Unlock()
+	if fake.FetchRefStub != nil {
+		return fake.FetchRefStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.fetchRefReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeGit) FetchRefCallCount() int {
+	fake.fetchRefMutex.RLock()
+	defer fake.fetchRefMutex.RUnlock()
+	return len(fake.fetchRefArgsForCall)
+}
+
+func (fake *FakeGit) FetchRefCalls(stub func(context.Context, string, int) error) {
+	fake.fetchRefMutex.Lock()
+	defer fake.fetchRefMutex.Unlock()
+	fake.FetchRefStub = stub
+}
+
+func (fake *FakeGit) FetchRefArgsForCall(i int) (context.Context, string, int) {
+	fake.fetchRefMutex.RLock()
+	defer fake.fetchRefMutex.RUnlock()
+	argsForCall := fake.fetchRefArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGit) FetchRefReturns(result1 error) {
+	fake.fetchRefMutex.Lock()
+	defer fake.fetchRefMutex.Unlock()
+	fake.FetchRefStub = nil
+	fake.fetchRefReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) FetchRefReturnsOnCall(i int, result1 error) {
+	fake.fetchRefMutex.Lock()
+	defer fake.fetchRefMutex.Unlock()
+	fake.FetchRefStub = nil
+	if fake.fetchRefReturnsOnCall == nil {
+		fake.fetchRefReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.fetchRefReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGit) GitCryptUnlock(arg1 context.Context, arg2 string) error {
 	fake.gitCryptUnlockMutex.Lock()
 	ret, specificReturn := fake.gitCryptUnlockReturnsOnCall[len(fake.gitCryptUnlockArgsForCall)]
 	fake.gitCryptUnlockArgsForCall = append(fake.gitCryptUnlockArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("GitCryptUnlock", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("GitCryptUnlock", []interface{}{arg1, arg2})
 	fake.gitCryptUnlockMutex.Unlock()
 	if fake.GitCryptUnlockStub != nil {
-		return fake.GitCryptUnlockStub(arg1)
+		return fake.GitCryptUnlockStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -328,17 +587,17 @@ func (fake *FakeGit) GitCryptUnlockCallCount() int {
 	return len(fake.gitCryptUnlockArgsForCall)
 }
 
-func (fake *FakeGit) GitCryptUnlockCalls(stub func(string) error) {
+func (fake *FakeGit) GitCryptUnlockCalls(stub func(context.Context, string) error) {
 	fake.gitCryptUnlockMutex.Lock()
 	defer fake.gitCryptUnlockMutex.Unlock()
 	fake.GitCryptUnlockStub = stub
 }
 
-func (fake *FakeGit) GitCryptUnlockArgsForCall(i int) string {
+func (fake *FakeGit) GitCryptUnlockArgsForCall(i int) (context.Context, string) {
 	fake.gitCryptUnlockMutex.RLock()
 	defer fake.gitCryptUnlockMutex.RUnlock()
 	argsForCall := fake.gitCryptUnlockArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) GitCryptUnlockReturns(result1 error) {
@@ -364,16 +623,17 @@ func (fake *FakeGit) GitCryptUnlockReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Init(arg1 string) error {
+func (fake *FakeGit) Init(arg1 context.Context, arg2 string) error {
 	fake.initMutex.Lock()
 	ret, specificReturn := fake.initReturnsOnCall[len(fake.initArgsForCall)]
 	fake.initArgsForCall = append(fake.initArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("Init", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("Init", []interface{}{arg1, arg2})
 	fake.initMutex.Unlock()
 	if fake.InitStub != nil {
-		return fake.InitStub(arg1)
+		return fake.InitStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -388,17 +648,17 @@ func (fake *FakeGit) InitCallCount() int {
 	return len(fake.initArgsForCall)
 }
 
-func (fake *FakeGit) InitCalls(stub func(string) error) {
+func (fake *FakeGit) InitCalls(stub func(context.Context, string) error) {
 	fake.initMutex.Lock()
 	defer fake.initMutex.Unlock()
 	fake.InitStub = stub
 }
 
-func (fake *FakeGit) InitArgsForCall(i int) string {
+func (fake *FakeGit) InitArgsForCall(i int) (context.Context, string) {
 	fake.initMutex.RLock()
 	defer fake.initMutex.RUnlock()
 	argsForCall := fake.initArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) InitReturns(result1 error) {
@@ -424,16 +684,17 @@ func (fake *FakeGit) InitReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Merge(arg1 string) error {
+func (fake *FakeGit) Merge(arg1 context.Context, arg2 string) error {
 	fake.mergeMutex.Lock()
 	ret, specificReturn := fake.mergeReturnsOnCall[len(fake.mergeArgsForCall)]
 	fake.mergeArgsForCall = append(fake.mergeArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("Merge", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("Merge", []interface{}{arg1, arg2})
 	fake.mergeMutex.Unlock()
 	if fake.MergeStub != nil {
-		return fake.MergeStub(arg1)
+		return fake.MergeStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -448,17 +709,17 @@ func (fake *FakeGit) MergeCallCount() int {
 	return len(fake.mergeArgsForCall)
 }
 
-func (fake *FakeGit) MergeCalls(stub func(string) error) {
+func (fake *FakeGit) MergeCalls(stub func(context.Context, string) error) {
 	fake.mergeMutex.Lock()
 	defer fake.mergeMutex.Unlock()
 	fake.MergeStub = stub
 }
 
-func (fake *FakeGit) MergeArgsForCall(i int) string {
+func (fake *FakeGit) MergeArgsForCall(i int) (context.Context, string) {
 	fake.mergeMutex.RLock()
 	defer fake.mergeMutex.RUnlock()
 	argsForCall := fake.mergeArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) MergeReturns(result1 error) {
@@ -484,18 +745,84 @@ func (fake *FakeGit) MergeReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Pull(arg1 string, arg2 string, arg3 int) error {
+func (fake *FakeGit) MergeBase(arg1 context.Context, arg2 string, arg3 string) (string, error) {
+	fake.mergeBaseMutex.Lock()
+	ret, specificReturn := fake.mergeBaseReturnsOnCall[len(fake.mergeBaseArgsForCall)]
+	fake.mergeBaseArgsForCall = append(fake.mergeBaseArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("MergeBase", []interface{}{arg1, arg2, arg3})
+	fake.mergeBaseMutex.Unlock()
+	if fake.MergeBaseStub != nil {
+		return fake.MergeBaseStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.mergeBaseReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGit) MergeBaseCallCount() int {
+	fake.mergeBaseMutex.RLock()
+	defer fake.mergeBaseMutex.RUnlock()
+	return len(fake.mergeBaseArgsForCall)
+}
+
+func (fake *FakeGit) MergeBaseCalls(stub func(context.Context, string, string) (string, error)) {
+	fake.mergeBaseMutex.Lock()
+	defer fake.mergeBaseMutex.Unlock()
+	fake.MergeBaseStub = stub
+}
+
+func (fake *FakeGit) MergeBaseArgsForCall(i int) (context.Context, string, string) {
+	fake.mergeBaseMutex.RLock()
+	defer fake.mergeBaseMutex.RUnlock()
+	argsForCall := fake.mergeBaseArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGit) MergeBaseReturns(result1 string, result2 error) {
+	fake.mergeBaseMutex.Lock()
+	defer fake.mergeBaseMutex.Unlock()
+	fake.MergeBaseStub = nil
+	fake.mergeBaseReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) MergeBaseReturnsOnCall(i int, result1 string, result2 error) {
+	fake.mergeBaseMutex.Lock()
+	defer fake.mergeBaseMutex.Unlock()
+	fake.MergeBaseStub = nil
+	if fake.mergeBaseReturnsOnCall == nil {
+		fake.mergeBaseReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.mergeBaseReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) Pull(arg1 context.Context, arg2 string, arg3 string, arg4 int) error {
 	fake.pullMutex.Lock()
 	ret, specificReturn := fake.pullReturnsOnCall[len(fake.pullArgsForCall)]
 	fake.pullArgsForCall = append(fake.pullArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-		arg3 int
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("Pull", []interface{}{arg1, arg2, arg3})
+		arg3 string
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Pull", []interface{}{arg1, arg2, arg3, arg4})
 	fake.pullMutex.Unlock()
 	if fake.PullStub != nil {
-		return fake.PullStub(arg1, arg2, arg3)
+		return fake.PullStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -510,17 +837,17 @@ func (fake *FakeGit) PullCallCount() int {
 	return len(fake.pullArgsForCall)
 }
 
-func (fake *FakeGit) PullCalls(stub func(string, string, int) error) {
+func (fake *FakeGit) PullCalls(stub func(context.Context, string, string, int) error) {
 	fake.pullMutex.Lock()
 	defer fake.pullMutex.Unlock()
 	fake.PullStub = stub
 }
 
-func (fake *FakeGit) PullArgsForCall(i int) (string, string, int) {
+func (fake *FakeGit) PullArgsForCall(i int) (context.Context, string, string, int) {
 	fake.pullMutex.RLock()
 	defer fake.pullMutex.RUnlock()
 	argsForCall := fake.pullArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeGit) PullReturns(result1 error) {
@@ -546,17 +873,18 @@ func (fake *FakeGit) PullReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Rebase(arg1 string, arg2 string) error {
+func (fake *FakeGit) Rebase(arg1 context.Context, arg2 string, arg3 string) error {
 	fake.rebaseMutex.Lock()
 	ret, specificReturn := fake.rebaseReturnsOnCall[len(fake.rebaseArgsForCall)]
 	fake.rebaseArgsForCall = append(fake.rebaseArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("Rebase", []interface{}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("Rebase", []interface{}{arg1, arg2, arg3})
 	fake.rebaseMutex.Unlock()
 	if fake.RebaseStub != nil {
-		return fake.RebaseStub(arg1, arg2)
+		return fake.RebaseStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -571,17 +899,17 @@ func (fake *FakeGit) RebaseCallCount() int {
 	return len(fake.rebaseArgsForCall)
 }
 
-func (fake *FakeGit) RebaseCalls(stub func(string, string) error) {
+func (fake *FakeGit) RebaseCalls(stub func(context.Context, string, string) error) {
 	fake.rebaseMutex.Lock()
 	defer fake.rebaseMutex.Unlock()
 	fake.RebaseStub = stub
 }
 
-func (fake *FakeGit) RebaseArgsForCall(i int) (string, string) {
+func (fake *FakeGit) RebaseArgsForCall(i int) (context.Context, string, string) {
 	fake.rebaseMutex.RLock()
 	defer fake.rebaseMutex.RUnlock()
 	argsForCall := fake.rebaseArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGit) RebaseReturns(result1 error) {
@@ -607,16 +935,17 @@ func (fake *FakeGit) RebaseReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) RevParse(arg1 string) (string, error) {
+func (fake *FakeGit) RevParse(arg1 context.Context, arg2 string) (string, error) {
 	fake.revParseMutex.Lock()
 	ret, specificReturn := fake.revParseReturnsOnCall[len(fake.revParseArgsForCall)]
 	fake.revParseArgsForCall = append(fake.revParseArgsForCall, struct {
-		arg1 string
-	}{arg1})
-	fake.recordInvocation("RevParse", []interface{}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("RevParse", []interface{}{arg1, arg2})
 	fake.revParseMutex.Unlock()
 	if fake.RevParseStub != nil {
-		return fake.RevParseStub(arg1)
+		return fake.RevParseStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -631,17 +960,17 @@ func (fake *FakeGit) RevParseCallCount() int {
 	return len(fake.revParseArgsForCall)
 }
 
-func (fake *FakeGit) RevParseCalls(stub func(string) (string, error)) {
+func (fake *FakeGit) RevParseCalls(stub func(context.Context, string) (string, error)) {
 	fake.revParseMutex.Lock()
 	defer fake.revParseMutex.Unlock()
 	fake.RevParseStub = stub
 }
 
-func (fake *FakeGit) RevParseArgsForCall(i int) string {
+func (fake *FakeGit) RevParseArgsForCall(i int) (context.Context, string) {
 	fake.revParseMutex.RLock()
 	defer fake.revParseMutex.RUnlock()
 	argsForCall := fake.revParseArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGit) RevParseReturns(result1 string, result2 error) {
@@ -673,18 +1002,26 @@ func (fake *FakeGit) RevParseReturnsOnCall(i int, result1 string, result2 error)
 func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.changedFilesMutex.RLock()
+	defer fake.changedFilesMutex.RUnlock()
 	fake.checkoutMutex.RLock()
 	defer fake.checkoutMutex.RUnlock()
 	fake.cloneMutex.RLock()
 	defer fake.cloneMutex.RUnlock()
 	fake.fetchMutex.RLock()
 	defer fake.fetchMutex.RUnlock()
+	fake.fetchCommitMutex.RLock()
+	defer fake.fetchCommitMutex.RUnlock()
+	fake.fetchRefMutex.RLock()
+	defer fake.fetchRefMutex.RUnlock()
 	fake.gitCryptUnlockMutex.RLock()
 	defer fake.gitCryptUnlockMutex.RUnlock()
 	fake.initMutex.RLock()
 	defer fake.initMutex.RUnlock()
 	fake.mergeMutex.RLock()
 	defer fake.mergeMutex.RUnlock()
+	fake.mergeBaseMutex.RLock()
+	defer fake.mergeBaseMutex.RUnlock()
 	fake.pullMutex.RLock()
 	defer fake.pullMutex.RUnlock()
 	fake.rebaseMutex.RLock()
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -10,10 +11,11 @@ import (
 )
 
 type FakeGithub struct {
-	GetChangedFilesStub        func(int) ([]string, error)
+	GetChangedFilesStub        func(context.Context, int) ([]string, error)
 	getChangedFilesMutex       sync.RWMutex
 	getChangedFilesArgsForCall []struct {
-		arg1 int
+		arg1 context.Context
+		arg2 int
 	}
 	getChangedFilesReturns struct {
 		result1 []string
@@ -23,11 +25,12 @@ type FakeGithub struct {
 		result1 []string
 		result2 error
 	}
-	GetPullRequestStub        func(int, string) (pullrequest.PullRequest, error)
+	GetPullRequestStub        func(context.Context, int, string) (pullrequest.PullRequest, error)
 	getPullRequestMutex       sync.RWMutex
 	getPullRequestArgsForCall []struct {
-		arg1 int
-		arg2 string
+		arg1 context.Context
+		arg2 int
+		arg3 string
 	}
 	getPullRequestReturns struct {
 		result1 pullrequest.PullRequest
@@ -37,10 +40,24 @@ type FakeGithub struct {
 		result1 pullrequest.PullRequest
 		result2 error
 	}
-	ListOpenPullRequestsStub        func(time.Time) ([]pullrequest.PullRequest, error)
+	ListMergeGroupsStub        func(context.Context) ([]pullrequest.MergeGroup, error)
+	listMergeGroupsMutex       sync.RWMutex
+	listMergeGroupsArgsForCall []struct {
+		arg1 context.Context
+	}
+	listMergeGroupsReturns struct {
+		result1 []pullrequest.MergeGroup
+		result2 error
+	}
+	listMergeGroupsReturnsOnCall map[int]struct {
+		result1 []pullrequest.MergeGroup
+		result2 error
+	}
+	ListOpenPullRequestsStub        func(context.Context, time.Time) ([]pullrequest.PullRequest, error)
 	listOpenPullRequestsMutex       sync.RWMutex
 	listOpenPullRequestsArgsForCall []struct {
-		arg1 time.Time
+		arg1 context.Context
+		arg2 time.Time
 	}
 	listOpenPullRequestsReturns struct {
 		result1 []pullrequest.PullRequest
@@ -50,11 +67,27 @@ type FakeGithub struct {
 		result1 []pullrequest.PullRequest
 		result2 error
 	}
-	PostCommentStub        func(int, string) error
+	ListOpenPullRequestsByRefStub        func(context.Context, string, string) ([]pullrequest.PullRequest, error)
+	listOpenPullRequestsByRefMutex       sync.RWMutex
+	listOpenPullRequestsByRefArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	listOpenPullRequestsByRefReturns struct {
+		result1 []pullrequest.PullRequest
+		result2 error
+	}
+	listOpenPullRequestsByRefReturnsOnCall map[int]struct {
+		result1 []pullrequest.PullRequest
+		result2 error
+	}
+	PostCommentStub        func(context.Context, int, string) error
 	postCommentMutex       sync.RWMutex
 	postCommentArgsForCall []struct {
-		arg1 int
-		arg2 string
+		arg1 context.Context
+		arg2 int
+		arg3 string
 	}
 	postCommentReturns struct {
 		result1 error
@@ -62,15 +95,16 @@ type FakeGithub struct {
 	postCommentReturnsOnCall map[int]struct {
 		result1 error
 	}
-	UpdateCommitStatusStub        func(string, string, string, string, string, string) error
+	UpdateCommitStatusStub        func(context.Context, string, string, string, string, string, string) error
 	updateCommitStatusMutex       sync.RWMutex
 	updateCommitStatusArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
 		arg5 string
 		arg6 string
+		arg7 string
 	}
 	updateCommitStatusReturns struct {
 		result1 error
@@ -82,16 +116,17 @@ type FakeGithub struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGithub) GetChangedFiles(arg1 int) ([]string, error) {
+func (fake *FakeGithub) GetChangedFiles(arg1 context.Context, arg2 int) ([]string, error) {
 	fake.getChangedFilesMutex.Lock()
 	ret, specificReturn := fake.getChangedFilesReturnsOnCall[len(fake.getChangedFilesArgsForCall)]
 	fake.getChangedFilesArgsForCall = append(fake.getChangedFilesArgsForCall, struct {
-		arg1 int
-	}{arg1})
-	fake.recordInvocation("GetChangedFiles", []interface{}{arg1})
+		arg1 context.Context
+		arg2 int
+	}{arg1, arg2})
+	fake.recordInvocation("GetChangedFiles", []interface{}{arg1, arg2})
 	fake.getChangedFilesMutex.Unlock()
 	if fake.GetChangedFilesStub != nil {
-		return fake.GetChangedFilesStub(arg1)
+		return fake.GetChangedFilesStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -106,17 +141,17 @@ func (fake *FakeGithub) GetChangedFilesCallCount() int {
 	return len(fake.getChangedFilesArgsForCall)
 }
 
-func (fake *FakeGithub) GetChangedFilesCalls(stub func(int) ([]string, error)) {
+func (fake *FakeGithub) GetChangedFilesCalls(stub func(context.Context, int) ([]string, error)) {
 	fake.getChangedFilesMutex.Lock()
 	defer fake.getChangedFilesMutex.Unlock()
 	fake.GetChangedFilesStub = stub
 }
 
-func (fake *FakeGithub) GetChangedFilesArgsForCall(i int) int {
+func (fake *FakeGithub) GetChangedFilesArgsForCall(i int) (context.Context, int) {
 	fake.getChangedFilesMutex.RLock()
 	defer fake.getChangedFilesMutex.RUnlock()
 	argsForCall := fake.getChangedFilesArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGithub) GetChangedFilesReturns(result1 []string, result2 error) {
@@ -145,17 +180,18 @@ func (fake *FakeGithub) GetChangedFilesReturnsOnCall(i int, result1 []string, re
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) GetPullRequest(arg1 int, arg2 string) (pullrequest.PullRequest, error) {
+func (fake *FakeGithub) GetPullRequest(arg1 context.Context, arg2 int, arg3 string) (pullrequest.PullRequest, error) {
 	fake.getPullRequestMutex.Lock()
 	ret, specificReturn := fake.getPullRequestReturnsOnCall[len(fake.getPullRequestArgsForCall)]
 	fake.getPullRequestArgsForCall = append(fake.getPullRequestArgsForCall, struct {
-		arg1 int
-		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("GetPullRequest", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 int
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("GetPullRequest", []interface{}{arg1, arg2, arg3})
 	fake.getPullRequestMutex.Unlock()
 	if fake.GetPullRequestStub != nil {
-		return fake.GetPullRequestStub(arg1, arg2)
+		return fake.GetPullRequestStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -170,17 +206,17 @@ func (fake *FakeGithub) GetPullRequestCallCount() int {
 	return len(fake.getPullRequestArgsForCall)
 }
 
-func (fake *FakeGithub) GetPullRequestCalls(stub func(int, string) (pullrequest.PullRequest, error)) {
+func (fake *FakeGithub) GetPullRequestCalls(stub func(context.Context, int, string) (pullrequest.PullRequest, error)) {
 	fake.getPullRequestMutex.Lock()
 	defer fake.getPullRequestMutex.Unlock()
 	fake.GetPullRequestStub = stub
 }
 
-func (fake *FakeGithub) GetPullRequestArgsForCall(i int) (int, string) {
+func (fake *FakeGithub) GetPullRequestArgsForCall(i int) (context.Context, int, string) {
 	fake.getPullRequestMutex.RLock()
 	defer fake.getPullRequestMutex.RUnlock()
 	argsForCall := fake.getPullRequestArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGithub) GetPullRequestReturns(result1 pullrequest.PullRequest, result2 error) {
@@ -209,16 +245,80 @@ func (fake *FakeGithub) GetPullRequestReturnsOnCall(i int, result1 pullrequest.P
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) ListOpenPullRequests(arg1 time.Time) ([]pullrequest.PullRequest, error) {
+func (fake *FakeGithub) ListMergeGroups(arg1 context.Context) ([]pullrequest.MergeGroup, error) {
+	fake.listMergeGroupsMutex.Lock()
+	ret, specificReturn := fake.listMergeGroupsReturnsOnCall[len(fake.listMergeGroupsArgsForCall)]
+	fake.listMergeGroupsArgsForCall = append(fake.listMergeGroupsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("ListMergeGroups", []interface{}{arg1})
+	fake.listMergeGroupsMutex.Unlock()
+	if fake.ListMergeGroupsStub != nil {
+		return fake.ListMergeGroupsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listMergeGroupsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGithub) ListMergeGroupsCallCount() int {
+	fake.listMergeGroupsMutex.RLock()
+	defer fake.listMergeGroupsMutex.RUnlock()
+	return len(fake.listMergeGroupsArgsForCall)
+}
+
+func (fake *FakeGithub) ListMergeGroupsCalls(stub func(context.Context) ([]pullrequest.MergeGroup, error)) {
+	fake.listMergeGroupsMutex.Lock()
+	defer fake.listMergeGroupsMutex.Unlock()
+	fake.ListMergeGroupsStub = stub
+}
+
+func (fake *FakeGithub) ListMergeGroupsArgsForCall(i int) context.Context {
+	fake.listMergeGroupsMutex.RLock()
+	defer fake.listMergeGroupsMutex.RUnlock()
+	argsForCall := fake.listMergeGroupsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeGithub) ListMergeGroupsReturns(result1 []pullrequest.MergeGroup, result2 error) {
+	fake.listMergeGroupsMutex.Lock()
+	defer fake.listMergeGroupsMutex.Unlock()
+	fake.ListMergeGroupsStub = nil
+	fake.listMergeGroupsReturns = struct {
+		result1 []pullrequest.MergeGroup
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) ListMergeGroupsReturnsOnCall(i int, result1 []pullrequest.MergeGroup, result2 error) {
+	fake.listMergeGroupsMutex.Lock()
+	defer fake.listMergeGroupsMutex.Unlock()
+	fake.ListMergeGroupsStub = nil
+	if fake.listMergeGroupsReturnsOnCall == nil {
+		fake.listMergeGroupsReturnsOnCall = make(map[int]struct {
+			result1 []pullrequest.MergeGroup
+			result2 error
+		})
+	}
+	fake.listMergeGroupsReturnsOnCall[i] = struct {
+		result1 []pullrequest.MergeGroup
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) ListOpenPullRequests(arg1 context.Context, arg2 time.Time) ([]pullrequest.PullRequest, error) {
 	fake.listOpenPullRequestsMutex.Lock()
 	ret, specificReturn := fake.listOpenPullRequestsReturnsOnCall[len(fake.listOpenPullRequestsArgsForCall)]
 	fake.listOpenPullRequestsArgsForCall = append(fake.listOpenPullRequestsArgsForCall, struct {
-		arg1 time.Time
-	}{arg1})
-	fake.recordInvocation("ListOpenPullRequests", []interface{}{arg1})
+		arg1 context.Context
+		arg2 time.Time
+	}{arg1, arg2})
+	fake.recordInvocation("ListOpenPullRequests", []interface{}{arg1, arg2})
 	fake.listOpenPullRequestsMutex.Unlock()
 	if fake.ListOpenPullRequestsStub != nil {
-		return fake.ListOpenPullRequestsStub(arg1)
+		return fake.ListOpenPullRequestsStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -233,17 +333,17 @@ func (fake *FakeGithub) ListOpenPullRequestsCallCount() int {
 	return len(fake.listOpenPullRequestsArgsForCall)
 }
 
-func (fake *FakeGithub) ListOpenPullRequestsCalls(stub func(time.Time) ([]pullrequest.PullRequest, error)) {
+func (fake *FakeGithub) ListOpenPullRequestsCalls(stub func(context.Context, time.Time) ([]pullrequest.PullRequest, error)) {
 	fake.listOpenPullRequestsMutex.Lock()
 	defer fake.listOpenPullRequestsMutex.Unlock()
 	fake.ListOpenPullRequestsStub = stub
 }
 
-func (fake *FakeGithub) ListOpenPullRequestsArgsForCall(i int) time.Time {
+func (fake *FakeGithub) ListOpenPullRequestsArgsForCall(i int) (context.Context, time.Time) {
 	fake.listOpenPullRequestsMutex.RLock()
 	defer fake.listOpenPullRequestsMutex.RUnlock()
 	argsForCall := fake.listOpenPullRequestsArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGithub) ListOpenPullRequestsReturns(result1 []pullrequest.PullRequest, result2 error) {
@@ -272,17 +372,83 @@ func (fake *FakeGithub) ListOpenPullRequestsReturnsOnCall(i int, result1 []pullr
 	}{result1, result2}
 }
 
-func (fake *FakeGithub) PostComment(arg1 int, arg2 string) error {
+func (fake *FakeGithub) ListOpenPullRequestsByRef(arg1 context.Context, arg2 string, arg3 string) ([]pullrequest.PullRequest, error) {
+	fake.listOpenPullRequestsByRefMutex.Lock()
+	ret, specificReturn := fake.listOpenPullRequestsByRefReturnsOnCall[len(fake.listOpenPullRequestsByRefArgsForCall)]
+	fake.listOpenPullRequestsByRefArgsForCall = append(fake.listOpenPullRequestsByRefArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("ListOpenPullRequestsByRef", []interface{}{arg1, arg2, arg3})
+	fake.listOpenPullRequestsByRefMutex.Unlock()
+	if fake.ListOpenPullRequestsByRefStub != nil {
+		return fake.ListOpenPullRequestsByRefStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listOpenPullRequestsByRefReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGithub) ListOpenPullRequestsByRefCallCount() int {
+	fake.listOpenPullRequestsByRefMutex.RLock()
+	defer fake.listOpenPullRequestsByRefMutex.RUnlock()
+	return len(fake.listOpenPullRequestsByRefArgsForCall)
+}
+
+func (fake *FakeGithub) ListOpenPullRequestsByRefCalls(stub func(context.Context, string, string) ([]pullrequest.PullRequest, error)) {
+	fake.listOpenPullRequestsByRefMutex.Lock()
+	defer fake.listOpenPullRequestsByRefMutex.Unlock()
+	fake.ListOpenPullRequestsByRefStub = stub
+}
+
+func (fake *FakeGithub) ListOpenPullRequestsByRefArgsForCall(i int) (context.Context, string, string) {
+	fake.listOpenPullRequestsByRefMutex.RLock()
+	defer fake.listOpenPullRequestsByRefMutex.RUnlock()
+	argsForCall := fake.listOpenPullRequestsByRefArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGithub) ListOpenPullRequestsByRefReturns(result1 []pullrequest.PullRequest, result2 error) {
+	fake.listOpenPullRequestsByRefMutex.Lock()
+	defer fake.listOpenPullRequestsByRefMutex.Unlock()
+	fake.ListOpenPullRequestsByRefStub = nil
+	fake.listOpenPullRequestsByRefReturns = struct {
+		result1 []pullrequest.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) ListOpenPullRequestsByRefReturnsOnCall(i int, result1 []pullrequest.PullRequest, result2 error) {
+	fake.listOpenPullRequestsByRefMutex.Lock()
+	defer fake.listOpenPullRequestsByRefMutex.Unlock()
+	fake.ListOpenPullRequestsByRefStub = nil
+	if fake.listOpenPullRequestsByRefReturnsOnCall == nil {
+		fake.listOpenPullRequestsByRefReturnsOnCall = make(map[int]struct {
+			result1 []pullrequest.PullRequest
+			result2 error
+		})
+	}
+	fake.listOpenPullRequestsByRefReturnsOnCall[i] = struct {
+		result1 []pullrequest.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGithub) PostComment(arg1 context.Context, arg2 int, arg3 string) error {
 	fake.postCommentMutex.Lock()
 	ret, specificReturn := fake.postCommentReturnsOnCall[len(fake.postCommentArgsForCall)]
 	fake.postCommentArgsForCall = append(fake.postCommentArgsForCall, struct {
-		arg1 int
-		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("PostComment", []interface{}{arg1, arg2})
+		arg1 context.Context
+		arg2 int
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("PostComment", []interface{}{arg1, arg2, arg3})
 	fake.postCommentMutex.Unlock()
 	if fake.PostCommentStub != nil {
-		return fake.PostCommentStub(arg1, arg2)
+		return fake.PostCommentStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -297,17 +463,17 @@ func (fake *FakeGithub) PostCommentCallCount() int {
 	return len(fake.postCommentArgsForCall)
 }
 
-func (fake *FakeGithub) PostCommentCalls(stub func(int, string) error) {
+func (fake *FakeGithub) PostCommentCalls(stub func(context.Context, int, string) error) {
 	fake.postCommentMutex.Lock()
 	defer fake.postCommentMutex.Unlock()
 	fake.PostCommentStub = stub
 }
 
-func (fake *FakeGithub) PostCommentArgsForCall(i int) (int, string) {
+func (fake *FakeGithub) PostCommentArgsForCall(i int) (context.Context, int, string) {
 	fake.postCommentMutex.RLock()
 	defer fake.postCommentMutex.RUnlock()
 	argsForCall := fake.postCommentArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeGithub) PostCommentReturns(result1 error) {
@@ -333,21 +499,22 @@ func (fake *FakeGithub) PostCommentReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGithub) UpdateCommitStatus(arg1 string, arg2 string, arg3 string, arg4 string, arg5 string, arg6 string) error {
+func (fake *FakeGithub) UpdateCommitStatus(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 string, arg6 string, arg7 string) error {
 	fake.updateCommitStatusMutex.Lock()
 	ret, specificReturn := fake.updateCommitStatusReturnsOnCall[len(fake.updateCommitStatusArgsForCall)]
 	fake.updateCommitStatusArgsForCall = append(fake.updateCommitStatusArgsForCall, struct {
-		arg1 string
+		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
 		arg5 string
 		arg6 string
-	}{arg1, arg2, arg3, arg4, arg5, arg6})
-	fake.recordInvocation("UpdateCommitStatus", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+		arg7 string
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.recordInvocation("UpdateCommitStatus", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
 	fake.updateCommitStatusMutex.Unlock()
 	if fake.UpdateCommitStatusStub != nil {
-		return fake.UpdateCommitStatusStub(arg1, arg2, arg3, arg4, arg5, arg6)
+		return fake.UpdateCommitStatusStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
 	}
 	if specificReturn {
 		return ret.result1
@@ -362,17 +529,17 @@ func (fake *FakeGithub) UpdateCommitStatusCallCount() int {
 	return len(fake.updateCommitStatusArgsForCall)
 }
 
-func (fake *FakeGithub) UpdateCommitStatusCalls(stub func(string, string, string, string, string, string) error) {
+func (fake *FakeGithub) UpdateCommitStatusCalls(stub func(context.Context, string, string, string, string, string, string) error) {
 	fake.updateCommitStatusMutex.Lock()
 	defer fake.updateCommitStatusMutex.Unlock()
 	fake.UpdateCommitStatusStub = stub
 }
 
-func (fake *FakeGithub) UpdateCommitStatusArgsForCall(i int) (string, string, string, string, string, string) {
+func (fake *FakeGithub) UpdateCommitStatusArgsForCall(i int) (context.Context, string, string, string, string, string, string) {
 	fake.updateCommitStatusMutex.RLock()
 	defer fake.updateCommitStatusMutex.RUnlock()
 	argsForCall := fake.updateCommitStatusArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
 }
 
 func (fake *FakeGithub) UpdateCommitStatusReturns(result1 error) {
@@ -405,8 +572,12 @@ func (fake *FakeGithub) Invocations() map[string][][]interface{} {
 	defer fake.getChangedFilesMutex.RUnlock()
 	fake.getPullRequestMutex.RLock()
 	defer fake.getPullRequestMutex.RUnlock()
+	fake.listMergeGroupsMutex.RLock()
+	defer fake.listMergeGroupsMutex.RUnlock()
 	fake.listOpenPullRequestsMutex.RLock()
 	defer fake.listOpenPullRequestsMutex.RUnlock()
+	fake.listOpenPullRequestsByRefMutex.RLock()
+	defer fake.listOpenPullRequestsByRefMutex.RUnlock()
 	fake.postCommentMutex.RLock()
 	defer fake.postCommentMutex.RUnlock()
 	fake.updateCommitStatusMutex.RLock()
@@ -1,88 +1,158 @@
 package resource
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
 // Git interface for testing purposes.
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/fake_git.go . Git
 type Git interface {
-	Init(string) error
-	Pull(string, string, int) error
-	Clone(string, string, int) error
-	RevParse(string) (string, error)
-	Fetch(int, int) error
-	Checkout(string, string) error
-	Merge(string) error
-	Rebase(string, string) error
-	GitCryptUnlock(string) error
+	Init(context.Context, string) error
+	Pull(context.Context, string, string, int) error
+	Clone(context.Context, string, string, int) error
+	RevParse(context.Context, string) (string, error)
+	Fetch(context.Context, int, int) error
+	FetchCommit(context.Context, string, int) error
+	FetchRef(context.Context, string, int) error
+	Checkout(context.Context, string, string) error
+	Merge(context.Context, string) error
+	Rebase(context.Context, string, string) error
+	GitCryptUnlock(context.Context, string) error
+	MergeBase(context.Context, string, string) (string, error)
+	ChangedFiles(context.Context, string, string) ([]string, error)
 }
 
 // NewGitClient ...
 func NewGitClient(source *Source, dir string, output io.Writer) (*GitClient, error) {
+	httpClient := &http.Client{}
 	if source.SkipSSLVerification {
 		os.Setenv("GIT_SSL_NO_VERIFY", "true")
 	}
+
+	transport, err := newTransport(source)
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		httpClient.Transport = transport
+	}
+
+	options, tlsDir, err := gitTLSOptions(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens oauth2.TokenSource
+	if source.appAuthentication() {
+		if tokens, err = newTokenSource(source, httpClient); err != nil {
+			return nil, err
+		}
+	}
+
 	return &GitClient{
-		AccessToken: source.AccessToken,
+		AccessToken: source.accessToken(),
+		TokenSource: tokens,
 		Directory:   dir,
 		Output:      output,
+		Timeout:     source.gitTimeout(),
+		Options:     options,
+		Env:         gitProxyEnv(source),
+		tlsDir:      tlsDir,
 	}, nil
 }
 
 // GitClient ...
 type GitClient struct {
 	AccessToken string
+	// TokenSource of installation tokens, when authenticating as a GitHub App
+	TokenSource oauth2.TokenSource
 	Directory   string
 	Output      io.Writer
+	// Timeout of each operation, commands are killed once it expires
+	Timeout time.Duration
+	// Options passed to each git command, e.g. the certificates to use
+	Options []string
+	// Env of each git command in addition to the environment of the resource
+	Env []string
+	// tlsDir holds the certificates written for git, see gitTLSOptions
+	tlsDir string
 }
 
-func (g *GitClient) command(name string, arg ...string) *exec.Cmd {
-	cmd := exec.Command(name, arg...)
+// Close removes the certificates written for git, the client can not be used afterwards.
+func (g *GitClient) Close() error {
+	if g.tlsDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(g.tlsDir); err != nil {
+		return fmt.Errorf("failed to remove certificates: %s", err)
+	}
+	return nil
+}
+
+func (g *GitClient) command(ctx context.Context, name string, arg ...string) *exec.Cmd {
+	if name == "git" {
+		arg = append(append([]string{}, g.Options...), arg...)
+	}
+	cmd := exec.CommandContext(ctx, name, arg...)
 	cmd.Dir = g.Directory
+	if len(g.Env) > 0 {
+		cmd.Env = append(os.Environ(), g.Env...)
+	}
 	cmd.Stdout = g.Output
 	cmd.Stderr = g.Output
 	return cmd
 }
 
 // Init ...
-func (g *GitClient) Init(branch string) error {
-	if err := g.command("git", "init").Run(); err != nil {
-		return fmt.Errorf("init failed: %s", err)
+func (g *GitClient) Init(ctx context.Context, branch string) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	if err := g.command(ctx, "git", "init").Run(); err != nil {
+		return timeoutError(ctx, "git init", g.Timeout, fmt.Errorf("init failed: %s", err))
 	}
-	if err := g.command("git", "checkout", "-b", branch).Run(); err != nil {
-		return fmt.Errorf("checkout to '%s' failed: %s", branch, err)
+	if err := g.command(ctx, "git", "checkout", "-b", branch).Run(); err != nil {
+		return timeoutError(ctx, "git init", g.Timeout, fmt.Errorf("checkout to '%s' failed: %s", branch, err))
 	}
 
 	log.Println("initialized repository:", branch)
 
-	return g.Config()
+	return timeoutError(ctx, "git init", g.Timeout, g.Config(ctx))
 }
 
 // Pull ...
-func (g *GitClient) Pull(uri, branch string, depth int) error {
+func (g *GitClient) Pull(ctx context.Context, uri, branch string, depth int) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	endpoint, err := g.Endpoint(uri)
 	if err != nil {
 		return err
 	}
 
-	if err := g.command("git", "remote", "add", "origin", endpoint+".git").Run(); err != nil {
-		return fmt.Errorf("failed to set remote origin: %s", err)
+	if err := g.command(ctx, "git", "remote", "add", "origin", endpoint+".git").Run(); err != nil {
+		return timeoutError(ctx, "git pull", g.Timeout, fmt.Errorf("failed to set remote origin: %s", err))
 	}
 
 	args := []string{"pull", "origin", branch}
 	args = appendDepth(args, depth)
-	cmd := g.command("git", args...)
+	cmd := g.command(ctx, "git", args...)
 
 	// Discard output to have zero chance of logging the access token.
 	cmd.Stdout = ioutil.Discard
@@ -91,18 +161,18 @@ func (g *GitClient) Pull(uri, branch string, depth int) error {
 	log.Println("pulling baseref:", args)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("clone failed: %s", err)
+		return timeoutError(ctx, "git pull", g.Timeout, fmt.Errorf("clone failed: %s", err))
 	}
 
 	return nil
 }
 
 // Config ...
-func (g *GitClient) Config() error {
-	if err := g.command("git", "config", "user.name", "concourse-ci").Run(); err != nil {
+func (g *GitClient) Config(ctx context.Context) error {
+	if err := g.command(ctx, "git", "config", "user.name", "concourse-ci").Run(); err != nil {
 		return fmt.Errorf("failed to configure git user: %s", err)
 	}
-	if err := g.command("git", "config", "user.email", "concourse@local").Run(); err != nil {
+	if err := g.command(ctx, "git", "config", "user.email", "concourse@local").Run(); err != nil {
 		return fmt.Errorf("failed to configure git email: %s", err)
 	}
 
@@ -110,7 +180,10 @@ func (g *GitClient) Config() error {
 }
 
 // Clone ...
-func (g *GitClient) Clone(uri, branch string, depth int) error {
+func (g *GitClient) Clone(ctx context.Context, uri, branch string, depth int) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	endpoint, err := g.Endpoint(uri)
 	if err != nil {
 		return err
@@ -118,7 +191,7 @@ func (g *GitClient) Clone(uri, branch string, depth int) error {
 
 	args := []string{"clone", endpoint + ".git", "-b", branch, "."}
 	args = appendDepth(args, depth)
-	cmd := g.command("git", args...)
+	cmd := g.command(ctx, "git", args...)
 
 	// Discard output to have zero chance of logging the access token.
 	cmd.Stdout = ioutil.Discard
@@ -127,25 +200,31 @@ func (g *GitClient) Clone(uri, branch string, depth int) error {
 	log.Println("cloning baseref:", args)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("clone failed: %s", err)
+		return timeoutError(ctx, "git clone", g.Timeout, fmt.Errorf("clone failed: %s", err))
 	}
 
-	return g.Config()
+	return timeoutError(ctx, "git clone", g.Timeout, g.Config(ctx))
 }
 
 // RevParse retrieves the SHA of the given branch.
-func (g *GitClient) RevParse(branch string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--verify", branch)
+func (g *GitClient) RevParse(ctx context.Context, branch string) (string, error) {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", branch)
 	cmd.Dir = g.Directory
 	sha, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("rev-parse '%s' failed: %s: %s", branch, err, string(sha))
+		return "", timeoutError(ctx, "git rev-parse", g.Timeout, fmt.Errorf("rev-parse '%s' failed: %s: %s", branch, err, string(sha)))
 	}
 	return strings.TrimSpace(string(sha)), nil
 }
 
 // Fetch ...
-func (g *GitClient) Fetch(prNumber, depth int) error {
+func (g *GitClient) Fetch(ctx context.Context, prNumber, depth int) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	args := []string{
 		"fetch",
 		"origin",
@@ -153,7 +232,7 @@ func (g *GitClient) Fetch(prNumber, depth int) error {
 		fmt.Sprintf("pull/%s/head", strconv.Itoa(prNumber)),
 	}
 	args = appendDepth(args, depth)
-	cmd := g.command("git", args...)
+	cmd := g.command(ctx, "git", args...)
 
 	// Discard output to have zero chance of logging the access token.
 	cmd.Stdout = ioutil.Discard
@@ -162,41 +241,95 @@ func (g *GitClient) Fetch(prNumber, depth int) error {
 	log.Println("fetching headref:", args)
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("fetch failed: %s", err)
+		return timeoutError(ctx, "git fetch", g.Timeout, fmt.Errorf("fetch failed: %s", err))
+	}
+	return nil
+}
+
+// FetchCommit fetches a single commit, e.g. one that is no longer part of the pull request.
+func (g *GitClient) FetchCommit(ctx context.Context, sha string, depth int) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	args := []string{"fetch", "origin", "-q", sha}
+	args = appendDepth(args, depth)
+	cmd := g.command(ctx, "git", args...)
+
+	// Discard output to have zero chance of logging the access token.
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = ioutil.Discard
+
+	log.Println("fetching commit:", args)
+
+	if err := cmd.Run(); err != nil {
+		return timeoutError(ctx, "git fetch commit", g.Timeout, fmt.Errorf("fetch commit failed: %s", err))
+	}
+	return nil
+}
+
+// FetchRef fetches a branch, e.g. the branch of a merge group.
+func (g *GitClient) FetchRef(ctx context.Context, ref string, depth int) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	args := []string{"fetch", "origin", "-q", ref}
+	args = appendDepth(args, depth)
+	cmd := g.command(ctx, "git", args...)
+
+	// Discard output to have zero chance of logging the access token.
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = ioutil.Discard
+
+	log.Println("fetching ref:", args)
+
+	if err := cmd.Run(); err != nil {
+		return timeoutError(ctx, "git fetch ref", g.Timeout, fmt.Errorf("fetch ref failed: %s", err))
 	}
 	return nil
 }
 
 // Checkout ...
-func (g *GitClient) Checkout(branch, sha string) error {
+func (g *GitClient) Checkout(ctx context.Context, branch, sha string) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	log.Println("checkout:", branch, sha)
-	if err := g.command("git", "checkout", "-b", "pr-"+branch, sha).Run(); err != nil {
-		return fmt.Errorf("checkout failed: %s", err)
+	if err := g.command(ctx, "git", "checkout", "-b", "pr-"+branch, sha).Run(); err != nil {
+		return timeoutError(ctx, "git checkout", g.Timeout, fmt.Errorf("checkout failed: %s", err))
 	}
 
 	return nil
 }
 
 // Merge ...
-func (g *GitClient) Merge(sha string) error {
+func (g *GitClient) Merge(ctx context.Context, sha string) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	log.Println("merging sha:", sha)
-	if err := g.command("git", "merge", sha, "--no-stat").Run(); err != nil {
-		return fmt.Errorf("merge failed: %s", err)
+	if err := g.command(ctx, "git", "merge", sha, "--no-stat").Run(); err != nil {
+		return timeoutError(ctx, "git merge", g.Timeout, fmt.Errorf("merge failed: %s", err))
 	}
 	return nil
 }
 
 // Rebase ...
-func (g *GitClient) Rebase(baseRef string, headSha string) error {
+func (g *GitClient) Rebase(ctx context.Context, baseRef string, headSha string) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	log.Println("rebasing:", baseRef, headSha)
-	if err := g.command("git", "rebase", baseRef, headSha).Run(); err != nil {
-		return fmt.Errorf("rebase failed: %s", err)
+	if err := g.command(ctx, "git", "rebase", baseRef, headSha).Run(); err != nil {
+		return timeoutError(ctx, "git rebase", g.Timeout, fmt.Errorf("rebase failed: %s", err))
 	}
 	return nil
 }
 
 // GitCryptUnlock unlocks the repository using git-crypt
-func (g *GitClient) GitCryptUnlock(base64key string) error {
+func (g *GitClient) GitCryptUnlock(ctx context.Context, base64key string) error {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
 	keyDir, err := ioutil.TempDir("", "")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary directory")
@@ -210,12 +343,66 @@ func (g *GitClient) GitCryptUnlock(base64key string) error {
 	if err := ioutil.WriteFile(keyPath, decodedKey, 600); err != nil {
 		return fmt.Errorf("failed to write git-crypt key to file: %s", err)
 	}
-	if err := g.command("git-crypt", "unlock", keyPath).Run(); err != nil {
-		return fmt.Errorf("git-crypt unlock failed: %s", err)
+	if err := g.command(ctx, "git-crypt", "unlock", keyPath).Run(); err != nil {
+		return timeoutError(ctx, "git-crypt unlock", g.Timeout, fmt.Errorf("git-crypt unlock failed: %s", err))
 	}
 	return nil
 }
 
+// MergeBase returns the best common ancestor of two commits.
+func (g *GitClient) MergeBase(ctx context.Context, a, b string) (string, error) {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "merge-base", a, b)
+	cmd.Dir = g.Directory
+	sha, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", timeoutError(ctx, "git merge-base", g.Timeout, fmt.Errorf("merge-base '%s' '%s' failed: %s: %s", a, b, err, string(sha)))
+	}
+	return strings.TrimSpace(string(sha)), nil
+}
+
+// ChangedFiles lists the files changed between two commits, renamed files are listed with both their old and new path.
+func (g *GitClient) ChangedFiles(ctx context.Context, base, head string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, g.Timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "diff", "--name-status", "-z", base, head)
+	cmd.Dir = g.Directory
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, timeoutError(ctx, "git diff", g.Timeout, fmt.Errorf("diff '%s' '%s' failed: %s: %s", base, head, err, stderr.String()))
+	}
+	return parseNameStatus(out), nil
+}
+
+// parseNameStatus parses the NUL separated output of `git diff --name-status -z`.
+func parseNameStatus(b []byte) []string {
+	files := []string{}
+	fields := strings.Split(strings.TrimSuffix(string(b), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields) && fields[i] != ""; {
+		switch fields[i][0] {
+		// renames and copies are followed by the source and destination path
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return files
+			}
+			if fields[i][0] == 'R' {
+				files = append(files, fields[i+1])
+			}
+			files = append(files, fields[i+2])
+			i += 3
+		default:
+			files = append(files, fields[i+1])
+			i += 2
+		}
+	}
+	return files
+}
+
 // Endpoint takes an uri and produces an endpoint with the login information baked in.
 func (g *GitClient) Endpoint(uri string) (string, error) {
 	endpoint, err := url.Parse(uri)
@@ -223,6 +410,13 @@ func (g *GitClient) Endpoint(uri string) (string, error) {
 		return "", fmt.Errorf("failed to parse commit url: %s", err)
 	}
 	endpoint.User = url.UserPassword("x-oauth-basic", g.AccessToken)
+	if g.TokenSource != nil {
+		token, err := g.TokenSource.Token()
+		if err != nil {
+			return "", err
+		}
+		endpoint.User = url.UserPassword("x-access-token", token.AccessToken)
+	}
 	return endpoint.String(), nil
 }
 
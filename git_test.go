@@ -46,3 +46,39 @@ func TestAppendDepth(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNameStatus(t *testing.T) {
+	tests := []struct {
+		description string
+		output      string
+		expected    []string
+	}{
+		{
+			description: "no changes",
+			output:      "",
+			expected:    []string{},
+		},
+		{
+			description: "modified, added and deleted",
+			output:      "M\x00README.md\x00A\x00docs/new file.md\x00D\x00main.go\x00",
+			expected:    []string{"README.md", "docs/new file.md", "main.go"},
+		},
+		{
+			description: "renamed includes both paths",
+			output:      "R087\x00docs/old.md\x00docs/new.md\x00M\x00README.md\x00",
+			expected:    []string{"docs/old.md", "docs/new.md", "README.md"},
+		},
+		{
+			description: "copied includes the destination",
+			output:      "C100\x00main.go\x00cmd/main.go\x00",
+			expected:    []string{"cmd/main.go"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			output := parseNameStatus([]byte(tc.output))
+			assert.Equal(t, tc.expected, output)
+		})
+	}
+}
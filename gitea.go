@@ -0,0 +1,510 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telia-oss/github-pr-resource/pullrequest"
+)
+
+// giteaPageSize is the number of items requested per page, the default maximum of Gitea.
+const giteaPageSize = 50
+
+// GiteaClient for handling requests to the Gitea (and Forgejo) REST API, which maps its responses
+// onto the same pull requests as the GithubClient.
+type GiteaClient struct {
+	Client     *http.Client
+	Endpoint   string
+	Repository string
+	Owner      string
+	// Statuses of head commits are only requested for skip_if_status
+	Statuses bool
+	// Reviews are only requested for required_review_approvals
+	Reviews bool
+	// RequestTimeout of each API request, including retries
+	RequestTimeout time.Duration
+}
+
+// NewGiteaClient ...
+func NewGiteaClient(s *Source) (*GiteaClient, error) {
+	repository, err := parseRepository(s.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	v3Endpoint, _ := s.endpoints()
+	endpoint, err := url.Parse(v3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse v3 endpoint: %s", err)
+	}
+
+	// Gitea accepts the bearer token set by the oauth2 transport
+	client, err := newHTTPClient(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.HTTPCache || s.HTTPCacheDir != "" {
+		log.Println("attaching http cache transport to client")
+		client = &http.Client{Transport: newHTTPCache(s, client.Transport)}
+	}
+
+	return &GiteaClient{
+		Client:         client,
+		Endpoint:       strings.TrimSuffix(endpoint.String(), "/"),
+		Owner:          repository.owner,
+		Repository:     repository.name,
+		Statuses:       len(s.SkipIfStatus) > 0,
+		Reviews:        s.RequiredReviewApprovals > 0,
+		RequestTimeout: s.requestTimeout(),
+	}, nil
+}
+
+// giteaPullRequest is a pull request as returned by the Gitea API.
+type giteaPullRequest struct {
+	ID        int64        `json:"id"`
+	Number    int          `json:"number"`
+	Title     string       `json:"title"`
+	URL       string       `json:"html_url"`
+	Base      giteaBranch  `json:"base"`
+	Head      giteaBranch  `json:"head"`
+	Labels    []giteaLabel `json:"labels"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type giteaBranch struct {
+	Ref        string `json:"ref"`
+	SHA        string `json:"sha"`
+	Repository struct {
+		ID  int64  `json:"id"`
+		URL string `json:"html_url"`
+	} `json:"repo"`
+}
+
+type giteaLabel struct {
+	Name string `json:"name"`
+}
+
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+type giteaStatus struct {
+	Context string `json:"context"`
+	State   string `json:"status"`
+}
+
+type giteaReview struct {
+	State     string `json:"state"`
+	Stale     bool   `json:"stale"`
+	Dismissed bool   `json:"dismissed"`
+}
+
+type giteaTimelineItem struct {
+	Type      string    `json:"type"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type giteaFile struct {
+	Filename string `json:"filename"`
+}
+
+// request sends a request to the repository API, decoding the JSON response into v unless it is nil.
+func (m *GiteaClient) request(ctx context.Context, name, method, path string, query url.Values, body, v interface{}) error {
+	_, err := m.send(ctx, name, method, path, query, body, v)
+	return err
+}
+
+// send is request, which also returns the headers of the response.
+func (m *GiteaClient) send(ctx context.Context, name, method, path string, query url.Values, body, v interface{}) (http.Header, error) {
+	ctx, cancel := withTimeout(ctx, m.RequestTimeout)
+	defer cancel()
+
+	u := fmt.Sprintf("%s/repos/%s/%s%s", m.Endpoint, url.PathEscape(m.Owner), url.PathEscape(m.Repository), path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %s", name, err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := m.Client.Do(req)
+	if err != nil {
+		return nil, timeoutError(ctx, name, m.RequestTimeout, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("%s failed: %s: %s", name, res.Status, strings.TrimSpace(string(b)))
+	}
+
+	if v == nil {
+		return res.Header, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return nil, timeoutError(ctx, name, m.RequestTimeout, fmt.Errorf("failed to decode %s: %s", name, err))
+	}
+	return res.Header, nil
+}
+
+// list calls f with each item of a list, page by page, until f returns false or all items are read.
+// Gitea caps the number of items per page (MAX_RESPONSE_ITEMS), so a page with fewer items than requested
+// is not necessarily the last. Paging stops once X-Total-Count items are read, or at an empty page.
+func (m *GiteaClient) list(ctx context.Context, name, path string, query url.Values, f func(json.RawMessage) (bool, error)) error {
+	read := 0
+	for n := 1; ; n++ {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("page", strconv.Itoa(n))
+		q.Set("limit", strconv.Itoa(giteaPageSize))
+
+		var items []json.RawMessage
+		header, err := m.send(ctx, name, http.MethodGet, path, q, nil, &items)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			more, err := f(item)
+			if err != nil || !more {
+				return err
+			}
+		}
+
+		read += len(items)
+		total, err := strconv.Atoi(header.Get("X-Total-Count"))
+		if len(items) == 0 || (err == nil && read >= total) {
+			return nil
+		}
+	}
+}
+
+// ListOpenPullRequests gets the last commit on all open pull requests updated since the given time. Gitea does not
+// embed the head commit or the events in pull requests, so each pull request costs a request for its head commit
+// and one for its timeline, plus one for its reviews and one for the statuses of its head if they are used.
+func (m *GiteaClient) ListOpenPullRequests(ctx context.Context, since time.Time) ([]pullrequest.PullRequest, error) {
+	var response []pullrequest.PullRequest
+
+	query := url.Values{"state": {"open"}, "sort": {"recentupdate"}}
+	err := m.list(ctx, "list pull requests", "/pulls", query, func(item json.RawMessage) (bool, error) {
+		var p giteaPullRequest
+		if err := json.Unmarshal(item, &p); err != nil {
+			return false, fmt.Errorf("failed to decode pull request: %s", err)
+		}
+
+		// Pull requests are sorted by the time they were last updated
+		if !p.UpdatedAt.After(since) {
+			return false, nil
+		}
+
+		pull, err := m.pullRequest(ctx, p, since, true)
+		if err != nil {
+			return false, err
+		}
+		response = append(response, pull)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ListOpenPullRequestsByRef gets the open pull requests with the given base and head refs, without their events.
+func (m *GiteaClient) ListOpenPullRequestsByRef(ctx context.Context, baseRefName, headRefName string) ([]pullrequest.PullRequest, error) {
+	var response []pullrequest.PullRequest
+
+	query := url.Values{"state": {"open"}}
+	err := m.list(ctx, "list pull requests", "/pulls", query, func(item json.RawMessage) (bool, error) {
+		var p giteaPullRequest
+		if err := json.Unmarshal(item, &p); err != nil {
+			return false, fmt.Errorf("failed to decode pull request: %s", err)
+		}
+
+		if (baseRefName != "" && p.Base.Ref != baseRefName) || (headRefName != "" && p.Head.Ref != headRefName) {
+			return true, nil
+		}
+
+		pull, err := m.pullRequest(ctx, p, time.Time{}, false)
+		if err != nil {
+			return false, err
+		}
+		response = append(response, pull)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ListMergeGroups is not supported, as Gitea has no merge queue.
+func (m *GiteaClient) ListMergeGroups(ctx context.Context) ([]pullrequest.MergeGroup, error) {
+	return nil, errors.New("merge queues are not supported by gitea")
+}
+
+// PostComment to a pull request or issue.
+func (m *GiteaClient) PostComment(ctx context.Context, number int, comment string) error {
+	body := map[string]string{"body": comment}
+	return m.request(ctx, "post comment", http.MethodPost, fmt.Sprintf("/issues/%d/comments", number), nil, body, nil)
+}
+
+// GetChangedFiles ...
+func (m *GiteaClient) GetChangedFiles(ctx context.Context, number int) ([]string, error) {
+	files := []string{}
+
+	err := m.list(ctx, "changed files", fmt.Sprintf("/pulls/%d/files", number), nil, func(item json.RawMessage) (bool, error) {
+		var f giteaFile
+		if err := json.Unmarshal(item, &f); err != nil {
+			return false, fmt.Errorf("failed to decode changed file: %s", err)
+		}
+		files = append(files, f.Filename)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// GetPullRequest returns the pull request with the given commit as HeadRef, an empty commitRef returns the current head.
+// A commit which is no longer part of the pull request (e.g. after a force push) is looked up in the repository.
+func (m *GiteaClient) GetPullRequest(ctx context.Context, number int, commitRef string) (pullrequest.PullRequest, error) {
+	var p giteaPullRequest
+	if err := m.request(ctx, "pull request", http.MethodGet, fmt.Sprintf("/pulls/%d", number), nil, nil, &p); err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+
+	pull, err := m.pullRequest(ctx, p, time.Now().Add(-pullRequestEventsLookback), true)
+	if err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+	if commitRef == "" || commitRef == pull.HeadRef.OID {
+		return pull, nil
+	}
+
+	var found *giteaCommit
+	err = m.list(ctx, "pull request commits", fmt.Sprintf("/pulls/%d/commits", number), nil, func(item json.RawMessage) (bool, error) {
+		var c giteaCommit
+		if err := json.Unmarshal(item, &c); err != nil {
+			return false, fmt.Errorf("failed to decode commit: %s", err)
+		}
+		if c.SHA == commitRef {
+			found = &c
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+	if found != nil {
+		pull.HeadRef, err = m.commit(ctx, *found)
+		if err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+		return pull, nil
+	}
+
+	log.Println("commit not found in pull request, looking up:", commitRef)
+
+	var c giteaCommit
+	if err := m.request(ctx, "commit", http.MethodGet, "/git/commits/"+url.PathEscape(commitRef), nil, nil, &c); err != nil {
+		return pullrequest.PullRequest{}, fmt.Errorf("commit with ref '%s' does not exist: %s", commitRef, err)
+	}
+
+	pull.HeadRef, err = m.commit(ctx, c)
+	if err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+	pull.HeadRefOrphaned = true
+
+	return pull, nil
+}
+
+// UpdateCommitStatus for a given commit.
+func (m *GiteaClient) UpdateCommitStatus(ctx context.Context, commitRef, baseContext, statusContext, status, targetURL, description string) error {
+	targetURL, description = commitStatusDetails(status, targetURL, description)
+
+	body := map[string]string{
+		"state":       strings.ToLower(status),
+		"target_url":  targetURL,
+		"description": description,
+		"context":     commitStatusContext(baseContext, statusContext),
+	}
+	return m.request(ctx, "update commit status", http.MethodPost, "/statuses/"+url.PathEscape(commitRef), nil, body, nil)
+}
+
+// pullRequest completes a pull request with its head commit, the approved reviews and optionally
+// the events and comments since the given time.
+func (m *GiteaClient) pullRequest(ctx context.Context, p giteaPullRequest, since time.Time, timeline bool) (pullrequest.PullRequest, error) {
+	var c giteaCommit
+	if err := m.request(ctx, "commit", http.MethodGet, "/git/commits/"+url.PathEscape(p.Head.SHA), nil, nil, &c); err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+
+	head, err := m.commit(ctx, c)
+	if err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+
+	labels := make([]string, 0, len(p.Labels))
+	for _, l := range p.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	pull := pullrequest.PullRequest{
+		ID:                strconv.FormatInt(p.ID, 10),
+		Number:            p.Number,
+		Title:             p.Title,
+		URL:               p.URL,
+		RepositoryURL:     p.Base.Repository.URL,
+		BaseRefName:       p.Base.Ref,
+		BaseRefOID:        p.Base.SHA,
+		HeadRefName:       p.Head.Ref,
+		IsCrossRepository: p.Head.Repository.ID != p.Base.Repository.ID,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+		HeadRef:           head,
+		HeadRefOID:        head.OID,
+		Events:            make([]pullrequest.Event, 0),
+		Commits:           make([]pullrequest.Commit, 0),
+		Comments:          make([]pullrequest.Comment, 0),
+		Labels:            labels,
+	}
+
+	if timeline {
+		if err := m.timeline(ctx, &pull, since); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+	}
+
+	if m.Reviews {
+		var reviews []giteaReview
+		if err := m.request(ctx, "reviews", http.MethodGet, fmt.Sprintf("/pulls/%d/reviews", p.Number), nil, nil, &reviews); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+		for _, r := range reviews {
+			if r.State == "APPROVED" && !r.Stale && !r.Dismissed {
+				pull.ApprovedReviewCount++
+			}
+		}
+	}
+
+	return pull, nil
+}
+
+// timeline adds the events and comments of the pull request since the given time. Gitea does not record
+// when commits were pushed, which is taken from the push events instead.
+func (m *GiteaClient) timeline(ctx context.Context, pull *pullrequest.PullRequest, since time.Time) error {
+	query := url.Values{"since": {since.Format(time.RFC3339)}}
+	return m.list(ctx, "timeline", fmt.Sprintf("/issues/%d/timeline", pull.Number), query, func(item json.RawMessage) (bool, error) {
+		var i giteaTimelineItem
+		if err := json.Unmarshal(item, &i); err != nil {
+			return false, fmt.Errorf("failed to decode timeline: %s", err)
+		}
+
+		switch i.Type {
+		case "comment":
+			pull.Comments = append(pull.Comments, pullrequest.Comment{CreatedAt: i.CreatedAt, Body: i.Body})
+		case "reopen":
+			pull.Events = append(pull.Events, pullrequest.Event{Type: pullrequest.ReopenedEvent, CreatedAt: i.CreatedAt})
+		case "change_target_branch":
+			pull.Events = append(pull.Events, pullrequest.Event{Type: pullrequest.BaseRefChangedEvent, CreatedAt: i.CreatedAt})
+		case "pull_push":
+			var push struct {
+				IsForcePush bool `json:"is_force_push"`
+			}
+			if err := json.Unmarshal([]byte(i.Body), &push); err == nil && push.IsForcePush {
+				pull.Events = append(pull.Events, pullrequest.Event{Type: pullrequest.HeadRefForcePushedEvent, CreatedAt: i.CreatedAt})
+			}
+			if i.CreatedAt.After(pull.HeadRef.PushedDate) {
+				pull.HeadRef.PushedDate = i.CreatedAt
+			}
+		}
+		return true, nil
+	})
+}
+
+// commit maps a commit, along with its statuses if they are used.
+func (m *GiteaClient) commit(ctx context.Context, c giteaCommit) (pullrequest.Commit, error) {
+	commit := pullrequest.Commit{
+		OID:           c.SHA,
+		AuthoredDate:  c.Commit.Author.Date,
+		CommittedDate: c.Commit.Committer.Date,
+		Message:       c.Commit.Message,
+		Author:        c.Commit.Author.Name,
+		Statuses:      make([]pullrequest.Status, 0),
+	}
+	if len(c.SHA) >= 7 {
+		commit.AbbreviatedOID = c.SHA[:7]
+	}
+	if c.Author != nil && c.Author.Login != "" {
+		commit.Author = c.Author.Login
+	}
+
+	if !m.Statuses {
+		return commit, nil
+	}
+
+	// The combined status holds the latest status of each context
+	var combined struct {
+		Statuses []giteaStatus `json:"statuses"`
+	}
+	if err := m.request(ctx, "commit status", http.MethodGet, fmt.Sprintf("/commits/%s/status", url.PathEscape(c.SHA)), nil, nil, &combined); err != nil {
+		return pullrequest.Commit{}, err
+	}
+
+	for _, s := range combined.Statuses {
+		commit.Statuses = append(commit.Statuses, pullrequest.Status{Context: s.Context, State: strings.ToUpper(s.State)})
+	}
+
+	return commit, nil
+}
@@ -0,0 +1,434 @@
+package resource_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+	"github.com/telia-oss/github-pr-resource/pullrequest"
+)
+
+// giteaResponses of the stand-in Gitea API, by path.
+var giteaResponses = map[string]string{
+	"/api/v1/repos/itsdalmo/test-repository/pulls": `[
+		{
+			"id": 102, "number": 2, "title": "Fork", "html_url": "https://gitea.example.com/itsdalmo/test-repository/pulls/2",
+			"base": {"ref": "master", "sha": "base2", "repo": {"id": 1, "html_url": "https://gitea.example.com/itsdalmo/test-repository"}},
+			"head": {"ref": "feature", "sha": "commit2", "repo": {"id": 2, "html_url": "https://gitea.example.com/fork/test-repository"}},
+			"labels": [],
+			"created_at": "2020-01-01T00:00:00Z", "updated_at": "2020-01-03T00:00:00Z"
+		},
+		{
+			"id": 101, "number": 1, "title": "Feature", "html_url": "https://gitea.example.com/itsdalmo/test-repository/pulls/1",
+			"base": {"ref": "develop", "sha": "base1", "repo": {"id": 1, "html_url": "https://gitea.example.com/itsdalmo/test-repository"}},
+			"head": {"ref": "feature", "sha": "commit1", "repo": {"id": 1, "html_url": "https://gitea.example.com/itsdalmo/test-repository"}},
+			"labels": [{"name": "enhancement"}],
+			"created_at": "2020-01-01T00:00:00Z", "updated_at": "2020-01-02T00:00:00Z"
+		}
+	]`,
+	"/api/v1/repos/itsdalmo/test-repository/pulls/1": `{
+		"id": 101, "number": 1, "title": "Feature", "html_url": "https://gitea.example.com/itsdalmo/test-repository/pulls/1",
+		"base": {"ref": "develop", "sha": "base1", "repo": {"id": 1, "html_url": "https://gitea.example.com/itsdalmo/test-repository"}},
+		"head": {"ref": "feature", "sha": "commit1", "repo": {"id": 1, "html_url": "https://gitea.example.com/itsdalmo/test-repository"}},
+		"labels": [{"name": "enhancement"}],
+		"created_at": "2020-01-01T00:00:00Z", "updated_at": "2020-01-02T00:00:00Z"
+	}`,
+	"/api/v1/repos/itsdalmo/test-repository/pulls/1/commits": `[
+		{"sha": "commit0", "commit": {"message": "initial", "author": {"name": "Kristian", "date": "2019-12-31T00:00:00Z"}, "committer": {"date": "2019-12-31T00:00:00Z"}}, "author": {"login": "itsdalmo"}},
+		{"sha": "commit1", "commit": {"message": "feature", "author": {"name": "Kristian", "date": "2020-01-01T00:00:00Z"}, "committer": {"date": "2020-01-01T00:00:00Z"}}, "author": {"login": "itsdalmo"}}
+	]`,
+	"/api/v1/repos/itsdalmo/test-repository/pulls/1/files": `[{"filename": "README.md"}, {"filename": "cmd/main.go"}]`,
+	"/api/v1/repos/itsdalmo/test-repository/pulls/1/reviews": `[
+		{"state": "APPROVED"}, {"state": "APPROVED", "stale": true}, {"state": "REQUEST_CHANGES"}
+	]`,
+	"/api/v1/repos/itsdalmo/test-repository/pulls/2/reviews": `[]`,
+	"/api/v1/repos/itsdalmo/test-repository/git/commits/commit0": `{
+		"sha": "commit0", "commit": {"message": "initial", "author": {"name": "Kristian", "date": "2019-12-31T00:00:00Z"}, "committer": {"date": "2019-12-31T00:00:00Z"}}, "author": {"login": "itsdalmo"}
+	}`,
+	"/api/v1/repos/itsdalmo/test-repository/git/commits/commit1": `{
+		"sha": "commit1", "commit": {"message": "feature", "author": {"name": "Kristian", "date": "2020-01-01T00:00:00Z"}, "committer": {"date": "2020-01-01T00:00:00Z"}}, "author": {"login": "itsdalmo"}
+	}`,
+	"/api/v1/repos/itsdalmo/test-repository/git/commits/commit2": `{
+		"sha": "commit2", "commit": {"message": "fork [skip ci]", "author": {"name": "Someone", "date": "2020-01-01T00:00:00Z"}, "committer": {"date": "2020-01-01T00:00:00Z"}}, "author": null
+	}`,
+	"/api/v1/repos/itsdalmo/test-repository/commits/commit1/status": `{"statuses": [{"context": "concourse-ci/status", "status": "success"}]}`,
+	"/api/v1/repos/itsdalmo/test-repository/commits/commit2/status": `{"statuses": []}`,
+	"/api/v1/repos/itsdalmo/test-repository/issues/1/timeline": `[
+		{"type": "comment", "body": "[build ci]", "created_at": "2020-01-02T00:00:00Z"},
+		{"type": "pull_push", "body": "{\"is_force_push\":true,\"commit_ids\":[\"commit0\",\"commit1\"]}", "created_at": "2020-01-02T00:00:00Z"},
+		{"type": "label", "created_at": "2020-01-02T00:00:00Z"}
+	]`,
+	"/api/v1/repos/itsdalmo/test-repository/issues/2/timeline": `[]`,
+	"/api/v1/repos/itsdalmo/test-repository/issues/1/comments": `{}`,
+	"/api/v1/repos/itsdalmo/test-repository/statuses/commit1":  `{}`,
+}
+
+// giteaRequest is a request received by the stand-in Gitea API.
+type giteaRequest struct {
+	Method        string
+	Path          string
+	Authorization string
+	Body          map[string]string
+}
+
+// newGiteaServer returns a stand-in Gitea API serving giteaResponses, which records the requests it receives.
+func newGiteaServer(t *testing.T, requests *[]giteaRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := giteaRequest{Method: r.Method, Path: r.URL.Path, Authorization: r.Header.Get("Authorization")}
+		if r.Method == http.MethodPost {
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(b, &request.Body))
+		}
+		*requests = append(*requests, request)
+
+		response, ok := giteaResponses[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		// Lists fit on the first page
+		if page := r.URL.Query().Get("page"); page != "" && page != "1" {
+			response = `[]`
+		}
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(response))
+	}))
+}
+
+func newGiteaSource(server *httptest.Server) resource.Source {
+	return resource.Source{
+		Repository:  server.URL + "/itsdalmo/test-repository",
+		Provider:    resource.ProviderGitea,
+		AccessToken: "oauthtoken",
+	}
+}
+
+func TestNewGiteaClient(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		expected    string
+	}{
+		{
+			description: "endpoint is derived from the repository url",
+			source:      resource.Source{Repository: "https://gitea.example.com/itsdalmo/test-repository.git", Provider: resource.ProviderGitea},
+			expected:    "https://gitea.example.com/api/v1",
+		},
+		{
+			description: "endpoint is derived from the repository ssh url",
+			source:      resource.Source{Repository: "git@gitea.example.com:itsdalmo/test-repository.git", Provider: resource.ProviderGitea},
+			expected:    "https://gitea.example.com/api/v1",
+		},
+		{
+			description: "configured endpoint is used",
+			source:      resource.Source{Repository: "itsdalmo/test-repository", Provider: resource.ProviderGitea, V3Endpoint: "https://git.example.com/gitea/api/v1/"},
+			expected:    "https://git.example.com/gitea/api/v1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			tc.source.AccessToken = "oauthtoken"
+			require.NoError(t, tc.source.Validate())
+
+			client, err := resource.NewClient(&tc.source)
+			require.NoError(t, err)
+			if assert.IsType(t, &resource.GiteaClient{}, client) {
+				assert.Equal(t, tc.expected, client.(*resource.GiteaClient).Endpoint)
+				assert.Equal(t, "itsdalmo", client.(*resource.GiteaClient).Owner)
+				assert.Equal(t, "test-repository", client.(*resource.GiteaClient).Repository)
+			}
+		})
+	}
+}
+
+func TestGiteaListOpenPullRequests(t *testing.T) {
+	var requests []giteaRequest
+	server := newGiteaServer(t, &requests)
+	defer server.Close()
+
+	source := newGiteaSource(server)
+	source.SkipIfStatus = []string{"concourse-ci/status"}
+	source.RequiredReviewApprovals = 1
+
+	client, err := resource.NewGiteaClient(&source)
+	require.NoError(t, err)
+
+	since := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	pulls, err := client.ListOpenPullRequests(context.Background(), since)
+	require.NoError(t, err)
+	require.Len(t, pulls, 2)
+
+	expected := pullrequest.PullRequest{
+		ID:            "101",
+		Number:        1,
+		Title:         "Feature",
+		URL:           "https://gitea.example.com/itsdalmo/test-repository/pulls/1",
+		RepositoryURL: "https://gitea.example.com/itsdalmo/test-repository",
+		BaseRefName:   "develop",
+		BaseRefOID:    "base1",
+		HeadRefName:   "feature",
+		CreatedAt:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		HeadRef: pullrequest.Commit{
+			OID:            "commit1",
+			AbbreviatedOID: "commit1",
+			AuthoredDate:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			CommittedDate:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			PushedDate:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			Message:        "feature",
+			Author:         "itsdalmo",
+			Statuses:       []pullrequest.Status{{Context: "concourse-ci/status", State: "SUCCESS"}},
+		},
+		HeadRefOID:          "commit1",
+		Events:              []pullrequest.Event{{Type: pullrequest.HeadRefForcePushedEvent, CreatedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		Comments:            []pullrequest.Comment{{Body: "[build ci]", CreatedAt: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		Commits:             []pullrequest.Commit{},
+		Labels:              []string{"enhancement"},
+		ApprovedReviewCount: 1,
+	}
+	assert.Equal(t, expected, pulls[1])
+
+	assert.Equal(t, 2, pulls[0].Number)
+	assert.True(t, pulls[0].IsCrossRepository)
+	assert.Equal(t, "Someone", pulls[0].HeadRef.Author)
+
+	for _, r := range requests {
+		assert.Equal(t, "Bearer oauthtoken", r.Authorization)
+	}
+
+	// Pull requests which were not updated since are not completed
+	requests = nil
+	pulls, err = client.ListOpenPullRequests(context.Background(), time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, pulls, 1)
+	assert.Equal(t, 2, pulls[0].Number)
+	for _, r := range requests {
+		assert.NotContains(t, r.Path, "/1/")
+	}
+}
+
+func TestGiteaGetPullRequest(t *testing.T) {
+	tests := []struct {
+		description string
+		commitRef   string
+		expected    string
+		orphaned    bool
+		err         string
+	}{
+		{
+			description: "returns the current head",
+			expected:    "commit1",
+		},
+		{
+			description: "returns an earlier commit of the pull request",
+			commitRef:   "commit0",
+			expected:    "commit0",
+		},
+		{
+			description: "returns an orphaned commit",
+			commitRef:   "commit2",
+			expected:    "commit2",
+			orphaned:    true,
+		},
+		{
+			description: "fails for a missing commit",
+			commitRef:   "missing",
+			err:         `commit with ref 'missing' does not exist: commit failed: 404 Not Found: {"message":"not found"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests []giteaRequest
+			server := newGiteaServer(t, &requests)
+			defer server.Close()
+
+			source := newGiteaSource(server)
+			client, err := resource.NewGiteaClient(&source)
+			require.NoError(t, err)
+
+			pull, err := client.GetPullRequest(context.Background(), 1, tc.commitRef)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, pull.Number)
+			assert.Equal(t, tc.expected, pull.HeadRef.OID)
+			assert.Equal(t, tc.orphaned, pull.HeadRefOrphaned)
+		})
+	}
+}
+
+func TestGiteaGetChangedFiles(t *testing.T) {
+	var requests []giteaRequest
+	server := newGiteaServer(t, &requests)
+	defer server.Close()
+
+	source := newGiteaSource(server)
+	client, err := resource.NewGiteaClient(&source)
+	require.NoError(t, err)
+
+	files, err := client.GetChangedFiles(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "cmd/main.go"}, files)
+}
+
+func TestGiteaPaging(t *testing.T) {
+	tests := []struct {
+		description string
+		totalCount  bool
+		requests    int
+	}{
+		{
+			description: "paging stops at the total count",
+			totalCount:  true,
+			requests:    3,
+		},
+		{
+			description: "paging stops at an empty page without a total count",
+			requests:    4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			// The server caps pages at 2 items (MAX_RESPONSE_ITEMS), fewer than requested
+			files := []string{"a.go", "b.go", "c.go", "d.go", "e.go"}
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				require.Equal(t, "/api/v1/repos/itsdalmo/test-repository/pulls/1/files", r.URL.Path)
+				require.Equal(t, "50", r.URL.Query().Get("limit"))
+
+				page, err := strconv.Atoi(r.URL.Query().Get("page"))
+				require.NoError(t, err)
+
+				items := []map[string]string{}
+				for i := (page - 1) * 2; i < page*2 && i < len(files); i++ {
+					items = append(items, map[string]string{"filename": files[i]})
+				}
+				if tc.totalCount {
+					w.Header().Set("X-Total-Count", strconv.Itoa(len(files)))
+				}
+				json.NewEncoder(w).Encode(items)
+			}))
+			defer server.Close()
+
+			source := newGiteaSource(server)
+			client, err := resource.NewGiteaClient(&source)
+			require.NoError(t, err)
+
+			changed, err := client.GetChangedFiles(context.Background(), 1)
+			require.NoError(t, err)
+			assert.Equal(t, files, changed)
+			assert.Equal(t, tc.requests, requests)
+		})
+	}
+}
+
+func TestGiteaCheck(t *testing.T) {
+	tests := []struct {
+		description string
+		source      func(*resource.Source)
+		expected    []int
+	}{
+		{
+			description: "skip ci and the base branch filter pull requests",
+			source:      func(s *resource.Source) { s.BaseBranch = "develop" },
+			expected:    []int{1},
+		},
+		{
+			description: "labels filter pull requests",
+			source:      func(s *resource.Source) { s.Labels = []string{"enhancement"} },
+			expected:    []int{1},
+		},
+		{
+			description: "statuses filter pull requests",
+			source:      func(s *resource.Source) { s.SkipIfStatus = []string{"concourse-ci/status"}; s.DisableCISkip = true },
+			expected:    []int{2},
+		},
+		{
+			description: "forks filter pull requests",
+			source:      func(s *resource.Source) { s.DisableForks = true; s.DisableCISkip = true },
+			expected:    []int{1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests []giteaRequest
+			server := newGiteaServer(t, &requests)
+			defer server.Close()
+
+			source := newGiteaSource(server)
+			tc.source(&source)
+			require.NoError(t, source.Validate())
+
+			client, err := resource.NewClient(&source)
+			require.NoError(t, err)
+
+			request := resource.CheckRequest{
+				Source:  source,
+				Version: resource.Version{PR: 3, Commit: "commit3", UpdatedDate: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)},
+			}
+			response, err := resource.Check(context.Background(), request, client)
+			require.NoError(t, err)
+
+			var actual []int
+			for _, v := range response {
+				actual = append(actual, v.PR)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGiteaPut(t *testing.T) {
+	var requests []giteaRequest
+	server := newGiteaServer(t, &requests)
+	defer server.Close()
+
+	source := newGiteaSource(server)
+	client, err := resource.NewGiteaClient(&source)
+	require.NoError(t, err)
+
+	require.NoError(t, client.PostComment(context.Background(), 1, "comment"))
+	require.NoError(t, client.UpdateCommitStatus(context.Background(), "commit1", "", "build", "SUCCESS", "https://concourse-ci.org", ""))
+
+	expected := []giteaRequest{
+		{
+			Method:        http.MethodPost,
+			Path:          "/api/v1/repos/itsdalmo/test-repository/issues/1/comments",
+			Authorization: "Bearer oauthtoken",
+			Body:          map[string]string{"body": "comment"},
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/api/v1/repos/itsdalmo/test-repository/statuses/commit1",
+			Authorization: "Bearer oauthtoken",
+			Body: map[string]string{
+				"state":       "success",
+				"target_url":  "https://concourse-ci.org",
+				"description": "Concourse CI build SUCCESS",
+				"context":     "concourse-ci/build",
+			},
+		},
+	}
+	assert.Equal(t, expected, requests)
+
+	assert.EqualError(t, client.PostComment(context.Background(), 2, "comment"),
+		`post comment failed: 404 Not Found: {"message":"not found"}`)
+}
@@ -2,15 +2,16 @@ package resource
 
 import (
 	"context"
-	"crypto/tls"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -22,45 +23,73 @@ import (
 // Github for testing purposes.
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/fake_github.go . Github
 type Github interface {
-	ListOpenPullRequests(prSince time.Time) ([]pullrequest.PullRequest, error)
-	PostComment(int, string) error
-	GetPullRequest(int, string) (pullrequest.PullRequest, error)
-	GetChangedFiles(int) ([]string, error)
-	UpdateCommitStatus(string, string, string, string, string, string) error
+	ListOpenPullRequests(ctx context.Context, prSince time.Time) ([]pullrequest.PullRequest, error)
+	PostComment(context.Context, int, string) error
+	GetPullRequest(context.Context, int, string) (pullrequest.PullRequest, error)
+	GetChangedFiles(context.Context, int) ([]string, error)
+	ListOpenPullRequestsByRef(context.Context, string, string) ([]pullrequest.PullRequest, error)
+	ListMergeGroups(context.Context) ([]pullrequest.MergeGroup, error)
+	UpdateCommitStatus(context.Context, string, string, string, string, string, string) error
 }
 
 // GithubClient for handling requests to the Github V3 and V4 APIs.
 type GithubClient struct {
+	// V3 is nil with graphql_only, when comments and commit statuses go through V4 as well
 	V3         *github.Client
 	V4         *githubv4.Client
 	Repository string
 	Owner      string
+	// RateLimitFloor fails queries once the remaining GraphQL rate limit drops below it
+	RateLimitFloor int
+	// RateLimit as of the last V4 query, and the total Cost of all V4 queries
+	RateLimit RateLimitObject
+	Cost      int
+	// mu guards RateLimit & Cost, as changed files are queried concurrently
+	mu sync.Mutex
+	// Skipped counts malformed pull requests which were left out of search results
+	Skipped int
+	// PrefetchFiles includes the first page of changed files in search results
+	PrefetchFiles bool
+	// QueryLabels & QueryReviews of pull requests in search results, which are only used by the labels &
+	// required_review_approvals filters of check. They are always queried for a single pull request.
+	QueryLabels  bool
+	QueryReviews bool
+	// QueryStatuses of the head commit of pull requests, which are only used by skip_if_status
+	QueryStatuses bool
+	// RequestTimeout of each API request, including retries
+	RequestTimeout time.Duration
+}
+
+// NewClient returns the client of the provider hosting the repository.
+func NewClient(s *Source) (Github, error) {
+	if s.Provider == ProviderGitea {
+		client, err := NewGiteaClient(s)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
+	client, err := NewGithubClient(s)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 // NewGithubClient ...
 func NewGithubClient(s *Source) (*GithubClient, error) {
-	owner, repository, err := parseRepository(s.Repository)
+	repository, err := parseRepository(s.Repository)
 	if err != nil {
 		return nil, err
 	}
+	v3Endpoint, v4Endpoint := s.endpoints()
 
-	ctx := context.TODO()
-	httpClient := http.Client{}
-
-	// Skip SSL verification for self-signed certificates
-	// source: https://github.com/google/go-github/pull/598#issuecomment-333039238
-	if s.SkipSSLVerification {
-		log.Println("disabling SSL verification")
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, &httpClient)
+	client, err := newHTTPClient(s)
+	if err != nil {
+		return nil, err
 	}
 
-	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: s.AccessToken},
-	))
-
 	if s.PreviewSchema {
 		log.Println("attaching preview schema transport to client")
 		client.Transport = &PreviewSchemaTransport{
@@ -69,8 +98,10 @@ func NewGithubClient(s *Source) (*GithubClient, error) {
 	}
 
 	var v3 *github.Client
-	if s.V3Endpoint != "" {
-		endpoint, err := url.Parse(s.V3Endpoint)
+	if s.GraphQLOnly {
+		log.Println("sending all requests through the v4 api")
+	} else if v3Endpoint != "" {
+		endpoint, err := url.Parse(v3Endpoint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse v3 endpoint: %s", err)
 		}
@@ -82,77 +113,325 @@ func NewGithubClient(s *Source) (*GithubClient, error) {
 		v3 = github.NewClient(client)
 	}
 
+	// The rate limit of mutations is only reported in the response headers
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	v4Client := &http.Client{Transport: &rateLimitTransport{base: base}}
+
 	var v4 *githubv4.Client
-	if s.V4Endpoint != "" {
-		endpoint, err := url.Parse(s.V4Endpoint)
+	if v4Endpoint != "" {
+		endpoint, err := url.Parse(v4Endpoint)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse v4 endpoint: %s", err)
 		}
-		v4 = githubv4.NewEnterpriseClient(endpoint.String(), client)
+		v4 = githubv4.NewEnterpriseClient(endpoint.String(), v4Client)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		v4 = githubv4.NewClient(client)
+		v4 = githubv4.NewClient(v4Client)
 	}
 
 	return &GithubClient{
-		V3:         v3,
-		V4:         v4,
-		Owner:      owner,
-		Repository: repository,
+		V3:             v3,
+		V4:             v4,
+		Owner:          repository.owner,
+		Repository:     repository.name,
+		RateLimitFloor: s.RateLimitFloor,
+		RequestTimeout: s.requestTimeout(),
+		PrefetchFiles:  len(s.Paths)+len(s.IgnorePaths) > 0 || s.SkipUnaffectedProjects,
+		QueryLabels:    len(s.Labels) > 0,
+		QueryReviews:   s.RequiredReviewApprovals > 0,
+		QueryStatuses:  len(s.SkipIfStatus) > 0,
 	}, nil
 }
 
-// ListOpenPullRequests gets the last commit on all open pull requests
-func (m *GithubClient) ListOpenPullRequests(since time.Time) ([]pullrequest.PullRequest, error) {
-	return m.searchOpenPullRequests(since, 100)
+// newHTTPClient returns the authenticated HTTP client of the API clients, which retries failed requests.
+func newHTTPClient(s *Source) (*http.Client, error) {
+	ctx := context.Background()
+	httpClient := http.Client{}
+
+	// Trust custom CAs, present a client certificate or skip SSL verification for self-signed certificates,
+	// and send requests through the proxy
+	// source: https://github.com/google/go-github/pull/598#issuecomment-333039238
+	transport, err := newTransport(s)
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		httpClient.Transport = transport
+	}
+
+	// A pool of tokens replaces the token set by the oauth2 transport
+	if len(s.AccessTokens) > 0 {
+		log.Println("attaching token pool transport to client:", len(s.AccessTokens))
+		httpClient.Transport = newTokenPool(s.AccessTokens, httpClient.Transport)
+	}
+
+	// Retry failed requests, including those which failed over to every token in the pool
+	httpClient.Transport = newRetryTransport(s, httpClient.Transport)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &httpClient)
+
+	tokens, err := newTokenSource(s, &httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(ctx, tokens), nil
 }
 
-func (m *GithubClient) searchOpenPullRequests(since time.Time, number int) ([]pullrequest.PullRequest, error) {
-	log.Println("building open pull requests query")
+// query executes a V4 query, recording the rate limit it reports. Queries fail fast once the remaining
+// rate limit is below the floor, rather than with an opaque error once it is exhausted.
+func (m *GithubClient) query(ctx context.Context, name string, q interface{}, rateLimit *RateLimitObject, vars map[string]interface{}) error {
+	if err := m.checkRateLimitFloor(); err != nil {
+		return err
+	}
 
-	var query struct {
-		Search struct {
-			Edges []struct {
-				Node struct {
-					PullRequestObject `graphql:"... on PullRequest"`
-				}
-			}
-			PageInfo struct {
-				EndCursor   githubv4.String
-				HasNextPage bool
-			}
-		} `graphql:"search(query:$q,type:ISSUE,last:$n,after:$c)"`
+	ctx, cancel := withTimeout(ctx, m.RequestTimeout)
+	defer cancel()
+
+	err := m.V4.Query(ctx, q, vars)
+	err = timeoutError(ctx, fmt.Sprintf("%s query", name), m.RequestTimeout, err)
+
+	m.recordRateLimit(name, rateLimit)
+	return err
+}
+
+// checkRateLimitFloor fails once the remaining rate limit of the last V4 query is below the floor.
+func (m *GithubClient) checkRateLimitFloor() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.RateLimitFloor > 0 && m.RateLimit.Remaining < m.RateLimitFloor && m.RateLimit.ResetAt.After(time.Now()) {
+		return fmt.Errorf("graphql rate limit below floor: %d points remaining (floor %d), resets at %s",
+			m.RateLimit.Remaining, m.RateLimitFloor, m.RateLimit.ResetAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// recordRateLimit of a V4 query, unless it was not part of the response.
+func (m *GithubClient) recordRateLimit(name string, rateLimit *RateLimitObject) {
+	if rateLimit.ResetAt.IsZero() {
+		return
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RateLimit = *rateLimit
+	m.Cost += rateLimit.Cost
+	log.Printf("graphql rate limit: query=%q cost=%d remaining=%d reset=%s\n",
+		name, rateLimit.Cost, rateLimit.Remaining, rateLimit.ResetAt.Format(time.RFC3339))
+}
+
+// ListOpenPullRequests gets the last commit on all open pull requests
+func (m *GithubClient) ListOpenPullRequests(ctx context.Context, since time.Time) ([]pullrequest.PullRequest, error) {
+	return m.searchOpenPullRequests(ctx, since, 100)
+}
+
+func (m *GithubClient) searchOpenPullRequests(ctx context.Context, since time.Time, number int) ([]pullrequest.PullRequest, error) {
+	log.Println("building open pull requests query")
+
+	// Only the fields required by the configuration are queried, which reduces the cost of the search.
+	// Including the first page of changed files saves a query per PR when they are needed.
 	vars := map[string]interface{}{
 		"c": (*githubv4.String)(nil),
 		"s": githubv4.DateTime{Time: since},
 		"n": githubv4.Int(number),
 		"q": githubv4.String(fmt.Sprintf("is:pr is:open repo:%s/%s updated:>%s sort:updated", m.Owner, m.Repository, since.Format(time.RFC3339))),
+
+		"withLabels":  githubv4.Boolean(m.QueryLabels),
+		"withReviews": githubv4.Boolean(m.QueryReviews),
+		"withStatus":  githubv4.Boolean(m.QueryStatuses),
+		"withFiles":   githubv4.Boolean(m.PrefetchFiles),
 	}
 
 	var response []pullrequest.PullRequest
 	for {
-		if err := m.V4.Query(context.TODO(), &query, vars); err != nil {
-			return nil, err
+		var query struct {
+			Search struct {
+				Edges []struct {
+					Node struct {
+						PullRequestObject      `graphql:"... on PullRequest"`
+						PullRequestFilesObject `graphql:"... on PullRequest @include(if:$withFiles)"`
+					}
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage bool
+				}
+			} `graphql:"search(query:$q,type:ISSUE,last:$n,after:$c)"`
+			RateLimit RateLimitObject
 		}
-		for _, p := range query.Search.Edges {
-			response = append(response, PullRequestFactory(p.Node.PullRequestObject))
+
+		err := m.query(ctx, "search open pull requests", &query, &query.RateLimit, vars)
+
+		var skipped int
+		for _, e := range query.Search.Edges {
+			p := e.Node.PullRequestObject
+			if p.Number == 0 || p.HeadRef.Target.OID == "" {
+				log.Printf("skipping malformed pull request: %+v\n", p)
+				skipped++
+				continue
+			}
+
+			pull := PullRequestFactory(p)
+			if m.PrefetchFiles {
+				pull.Files, pull.FilesComplete = filesFactory(e.Node.PullRequestFilesObject)
+			}
+			response = append(response, pull)
+		}
+		if err != nil {
+			// Errors scoped to a single PR (e.g. a deleted head repository) null its node in otherwise partial data,
+			// other errors could have dropped PRs from the results without a trace
+			if skipped == 0 {
+				return nil, err
+			}
+			log.Println("accepting partial search results:", err)
 		}
+		m.Skipped += skipped
 		if number < 100 || !query.Search.PageInfo.HasNextPage {
 			break
 		}
 		vars["c"] = query.Search.PageInfo.EndCursor
 	}
+
+	if m.Skipped > 0 {
+		log.Println("malformed pull requests skipped:", m.Skipped)
+	}
+	return response, nil
+}
+
+// ListOpenPullRequestsByRef lists the open pull requests with the given base and head ref names,
+// an empty ref name matches any ref. E.g. the PRs stacked on another PR have its head ref as base ref.
+func (m *GithubClient) ListOpenPullRequestsByRef(ctx context.Context, baseRefName, headRefName string) ([]pullrequest.PullRequest, error) {
+	log.Println("building open pull requests by ref query")
+
+	var query struct {
+		Repository struct {
+			PullRequests struct {
+				Edges []struct {
+					Node struct {
+						PullRequestObject
+					}
+				}
+			} `graphql:"pullRequests(first:100,states:OPEN,baseRefName:$base,headRefName:$head)"`
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
+	}
+
+	vars := map[string]interface{}{
+		"owner": githubv4.String(m.Owner),
+		"name":  githubv4.String(m.Repository),
+		"base":  (*githubv4.String)(nil),
+		"head":  (*githubv4.String)(nil),
+		"s":     githubv4.DateTime{Time: time.Now()},
+
+		"withLabels":  githubv4.Boolean(true),
+		"withReviews": githubv4.Boolean(true),
+		"withStatus":  githubv4.Boolean(m.QueryStatuses),
+	}
+	if baseRefName != "" {
+		vars["base"] = githubv4.NewString(githubv4.String(baseRefName))
+	}
+	if headRefName != "" {
+		vars["head"] = githubv4.NewString(githubv4.String(headRefName))
+	}
+
+	if err := m.query(ctx, "open pull requests by ref", &query, &query.RateLimit, vars); err != nil {
+		return nil, err
+	}
+
+	var response []pullrequest.PullRequest
+	for _, e := range query.Repository.PullRequests.Edges {
+		response = append(response, PullRequestFactory(e.Node.PullRequestObject))
+	}
+
+	return response, nil
+}
+
+// mergeGroupRefPrefix is the prefix of the branches GitHub creates for the merge groups of a merge queue
+const mergeGroupRefPrefix = "gh-readonly-queue/"
+
+// mergeGroupRef matches the merge group branch names (without prefix), e.g. "main/pr-123-<sha of the base>"
+var mergeGroupRef = regexp.MustCompile(`^(.+)/pr-(\d+)-[0-9a-f]+$`)
+
+// ListMergeGroups lists the merge groups of the merge queue, using the branches GitHub creates for them.
+func (m *GithubClient) ListMergeGroups(ctx context.Context) ([]pullrequest.MergeGroup, error) {
+	log.Println("building merge groups query")
+
+	var query struct {
+		Repository struct {
+			Refs struct {
+				Edges []struct {
+					Node struct {
+						Name   string
+						Target struct {
+							CommitObject `graphql:"... on Commit"`
+						}
+					}
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage bool
+				}
+			} `graphql:"refs(refPrefix:$prefix,first:100,after:$c)"`
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
+	}
+
+	vars := map[string]interface{}{
+		"owner":  githubv4.String(m.Owner),
+		"name":   githubv4.String(m.Repository),
+		"prefix": githubv4.String("refs/heads/" + mergeGroupRefPrefix),
+		"c":      (*githubv4.String)(nil),
+	}
+
+	var response []pullrequest.MergeGroup
+	for {
+		query.Repository.Refs.Edges = nil
+		if err := m.query(ctx, "merge groups", &query, &query.RateLimit, vars); err != nil {
+			return nil, err
+		}
+
+		for _, e := range query.Repository.Refs.Edges {
+			match := mergeGroupRef.FindStringSubmatch(e.Node.Name)
+			if match == nil {
+				log.Println("skipping unknown merge group ref:", e.Node.Name)
+				continue
+			}
+			number, _ := strconv.Atoi(match[2])
+
+			response = append(response, pullrequest.MergeGroup{
+				Ref:         mergeGroupRefPrefix + e.Node.Name,
+				Number:      number,
+				BaseRefName: match[1],
+				HeadRef:     commitFactory(e.Node.Target.CommitObject),
+			})
+		}
+
+		if !query.Repository.Refs.PageInfo.HasNextPage {
+			break
+		}
+		vars["c"] = query.Repository.Refs.PageInfo.EndCursor
+	}
+
 	return response, nil
 }
 
 // PostComment to a pull request or issue.
-func (m *GithubClient) PostComment(number int, comment string) error {
+func (m *GithubClient) PostComment(ctx context.Context, number int, comment string) error {
+	if m.V3 == nil {
+		return m.addComment(ctx, number, comment)
+	}
+
+	ctx, cancel := withTimeout(ctx, m.RequestTimeout)
+	defer cancel()
+
 	_, _, err := m.V3.Issues.CreateComment(
-		context.TODO(),
+		ctx,
 		m.Owner,
 		m.Repository,
 		number,
@@ -160,11 +439,11 @@ func (m *GithubClient) PostComment(number int, comment string) error {
 			Body: github.String(comment),
 		},
 	)
-	return err
+	return timeoutError(ctx, "post comment", m.RequestTimeout, err)
 }
 
 // GetChangedFiles ...
-func (m *GithubClient) GetChangedFiles(number int) ([]string, error) {
+func (m *GithubClient) GetChangedFiles(ctx context.Context, number int) ([]string, error) {
 	log.Println("building pull request changed files query")
 
 	var filequery struct {
@@ -183,6 +462,7 @@ func (m *GithubClient) GetChangedFiles(number int) ([]string, error) {
 				} `graphql:"files(first:100, after: $c)"`
 			} `graphql:"pullRequest(number:$n)"`
 		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
 	}
 
 	files := []string{}
@@ -196,7 +476,7 @@ func (m *GithubClient) GetChangedFiles(number int) ([]string, error) {
 			"c":     githubv4.String(cursor),
 		}
 
-		if err := m.V4.Query(context.TODO(), &filequery, vars); err != nil {
+		if err := m.query(ctx, "changed files", &filequery, &filequery.RateLimit, vars); err != nil {
 			return nil, err
 		}
 
@@ -214,71 +494,163 @@ func (m *GithubClient) GetChangedFiles(number int) ([]string, error) {
 	return files, nil
 }
 
-// GetPullRequest ...
-func (m *GithubClient) GetPullRequest(number int, commitRef string) (pullrequest.PullRequest, error) {
+// pullRequestEventsLookback is how far back the events of a single pull request are looked up. They are only
+// written to the metadata of get, so older events are left out rather than paging through a long timeline.
+const pullRequestEventsLookback = 365 * 24 * time.Hour
+
+// GetPullRequest returns the pull request with the given commit as HeadRef, an empty commitRef returns the current head.
+// A commit which is no longer part of the pull request (e.g. after a force push) is looked up in the repository.
+func (m *GithubClient) GetPullRequest(ctx context.Context, number int, commitRef string) (pullrequest.PullRequest, error) {
 	log.Println("building pull request query")
 
 	var query struct {
 		Repository struct {
 			PullRequest struct {
 				PullRequestObject
-				Commits struct {
-					Edges []struct {
-						Node struct {
-							Commit CommitObject
-						}
-					}
-				} `graphql:"commits(last:$last)"`
+				Commits commitsConnection `graphql:"commits(last:$last)"`
 			} `graphql:"pullRequest(number:$number)"`
 		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
 	}
 
 	vars := map[string]interface{}{
-		"s":      githubv4.DateTime{Time: time.Now().AddDate(-1, 0, 0)},
+		"s":      githubv4.DateTime{Time: time.Now().Add(-pullRequestEventsLookback)},
 		"owner":  githubv4.String(m.Owner),
 		"name":   githubv4.String(m.Repository),
 		"number": githubv4.Int(number),
 		"last":   githubv4.Int(100),
+
+		"withLabels":  githubv4.Boolean(true),
+		"withReviews": githubv4.Boolean(true),
+		"withStatus":  githubv4.Boolean(m.QueryStatuses),
 	}
 
-	// TODO: Pagination - in case someone pushes > 100 commits before the build has time to start :p
-	if err := m.V4.Query(context.TODO(), &query, vars); err != nil {
+	if err := m.query(ctx, "pull request", &query, &query.RateLimit, vars); err != nil {
 		return pullrequest.PullRequest{}, err
 	}
 
-	for _, c := range query.Repository.PullRequest.Commits.Edges {
-		if c.Node.Commit.OID == commitRef {
-			// Return as soon as we find the correct ref.
-			pull := PullRequestFactory(query.Repository.PullRequest.PullRequestObject)
-			pull.HeadRef = commitFactory(c.Node.Commit)
-			return pull, nil
+	pull := PullRequestFactory(query.Repository.PullRequest.PullRequestObject)
+	if commitRef == "" {
+		return pull, nil
+	}
+
+	commits := query.Repository.PullRequest.Commits
+	for {
+		for _, c := range commits.Edges {
+			if c.Node.Commit.OID == commitRef {
+				// Return as soon as we find the correct ref.
+				pull.HeadRef = commitFactory(c.Node.Commit)
+				return pull, nil
+			}
+		}
+
+		if !commits.PageInfo.HasPreviousPage {
+			break
+		}
+
+		var err error
+		commits, err = m.getPullRequestCommits(ctx, number, commits.PageInfo.StartCursor)
+		if err != nil {
+			return pullrequest.PullRequest{}, err
 		}
 	}
 
-	// Return an error if the commit was not found
-	return pullrequest.PullRequest{}, fmt.Errorf("commit with ref '%s' does not exist", commitRef)
+	log.Println("commit not found in pull request, looking up:", commitRef)
+
+	commit, err := m.getCommit(ctx, commitRef)
+	if err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+
+	pull.HeadRef = commit
+	pull.HeadRefOrphaned = true
+
+	return pull, nil
 }
 
-// UpdateCommitStatus for a given commit (not supported by V4 API).
-func (m *GithubClient) UpdateCommitStatus(commitRef, baseContext, statusContext, status, targetURL, description string) error {
-	if baseContext == "" {
-		baseContext = "concourse-ci"
+// commitsConnection represents a page of the GraphQL pull request commits connection, paged backwards.
+type commitsConnection struct {
+	Edges []struct {
+		Node struct {
+			Commit CommitObject
+		}
+	}
+	PageInfo struct {
+		StartCursor     githubv4.String
+		HasPreviousPage bool
 	}
+}
+
+func (m *GithubClient) getPullRequestCommits(ctx context.Context, number int, before githubv4.String) (commitsConnection, error) {
+	log.Println("building pull request commits query")
 
-	if statusContext == "" {
-		statusContext = "status"
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				Commits commitsConnection `graphql:"commits(last:100,before:$c)"`
+			} `graphql:"pullRequest(number:$number)"`
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
 	}
 
-	if targetURL == "" {
-		targetURL = strings.Join([]string{os.Getenv("ATC_EXTERNAL_URL"), "builds", os.Getenv("BUILD_ID")}, "/")
+	vars := map[string]interface{}{
+		"owner":  githubv4.String(m.Owner),
+		"name":   githubv4.String(m.Repository),
+		"number": githubv4.Int(number),
+		"c":      before,
 	}
 
-	if description == "" {
-		description = fmt.Sprintf("Concourse CI build %s", status)
+	if err := m.query(ctx, "pull request commits", &query, &query.RateLimit, vars); err != nil {
+		return commitsConnection{}, err
+	}
+
+	return query.Repository.PullRequest.Commits, nil
+}
+
+func (m *GithubClient) getCommit(ctx context.Context, commitRef string) (pullrequest.Commit, error) {
+	log.Println("building commit query")
+
+	var query struct {
+		Repository struct {
+			Object struct {
+				CommitObject `graphql:"... on Commit"`
+			} `graphql:"object(oid:$oid)"`
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
+	}
+
+	vars := map[string]interface{}{
+		"owner": githubv4.String(m.Owner),
+		"name":  githubv4.String(m.Repository),
+		"oid":   githubv4.GitObjectID(commitRef),
 	}
 
+	if err := m.query(ctx, "commit", &query, &query.RateLimit, vars); err != nil {
+		return pullrequest.Commit{}, err
+	}
+
+	// Return an error if the commit was not found
+	if query.Repository.Object.OID == "" {
+		return pullrequest.Commit{}, fmt.Errorf("commit with ref '%s' does not exist", commitRef)
+	}
+
+	return commitFactory(query.Repository.Object.CommitObject), nil
+}
+
+// UpdateCommitStatus for a given commit. The V4 API has no mutation for commit statuses,
+// so they are created as check runs with graphql_only.
+func (m *GithubClient) UpdateCommitStatus(ctx context.Context, commitRef, baseContext, statusContext, status, targetURL, description string) error {
+	targetURL, description = commitStatusDetails(status, targetURL, description)
+
+	if m.V3 == nil {
+		return m.createCheckRun(ctx, commitRef, commitStatusContext(baseContext, statusContext), status, targetURL, description)
+	}
+
+	ctx, cancel := withTimeout(ctx, m.RequestTimeout)
+	defer cancel()
+
 	_, _, err := m.V3.Repositories.CreateStatus(
-		context.TODO(),
+		ctx,
 		m.Owner,
 		m.Repository,
 		commitRef,
@@ -286,18 +658,194 @@ func (m *GithubClient) UpdateCommitStatus(commitRef, baseContext, statusContext,
 			State:       github.String(strings.ToLower(status)),
 			TargetURL:   github.String(targetURL),
 			Description: github.String(description),
-			Context:     github.String(path.Join(baseContext, statusContext)),
+			Context:     github.String(commitStatusContext(baseContext, statusContext)),
 		},
 	)
+	return timeoutError(ctx, "update commit status", m.RequestTimeout, err)
+}
+
+// mutate executes a V4 mutation, which fails fast once the remaining rate limit is below the floor like query.
+// Mutations can not query the rate limit, which is recorded from the response headers instead.
+func (m *GithubClient) mutate(ctx context.Context, name string, mutation interface{}, input githubv4.Input) error {
+	if err := m.checkRateLimitFloor(); err != nil {
+		return err
+	}
+
+	var rateLimit RateLimitObject
+	ctx, cancel := withTimeout(context.WithValue(ctx, rateLimitHeadersKey{}, &rateLimit), m.RequestTimeout)
+	defer cancel()
+
+	err := m.V4.Mutate(ctx, mutation, input, nil)
+	err = timeoutError(ctx, fmt.Sprintf("%s mutation", name), m.RequestTimeout, err)
+
+	m.recordRateLimit(name, &rateLimit)
 	return err
 }
 
-func parseRepository(s string) (string, string, error) {
-	parts := strings.Split(s, "/")
-	if len(parts) != 2 {
-		return "", "", errors.New("malformed repository")
+// rateLimitHeadersKey is the context key of a *RateLimitObject, which rateLimitTransport sets from the response.
+type rateLimitHeadersKey struct{}
+
+// rateLimitTransport records the rate limit reported by the X-RateLimit-* headers of a V4 response, for the
+// requests which ask for it with rateLimitHeadersKey.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	rateLimit, ok := req.Context().Value(rateLimitHeadersKey{}).(*RateLimitObject)
+	if err != nil || !ok {
+		return res, err
+	}
+
+	remaining, err := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return res, nil
+	}
+	reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return res, nil
+	}
+
+	// The cost of a mutation is not reported, but it is at least one point
+	*rateLimit = RateLimitObject{Cost: 1, Remaining: remaining, ResetAt: githubv4.DateTime{Time: time.Unix(reset, 0)}}
+	return res, nil
+}
+
+// addComment posts a comment to a pull request with the V4 API, which requires its node ID.
+func (m *GithubClient) addComment(ctx context.Context, number int, comment string) error {
+	var query struct {
+		Repository struct {
+			PullRequest struct {
+				ID githubv4.ID
+			} `graphql:"pullRequest(number:$number)"`
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
+	}
+
+	vars := map[string]interface{}{
+		"owner":  githubv4.String(m.Owner),
+		"name":   githubv4.String(m.Repository),
+		"number": githubv4.Int(number),
+	}
+
+	if err := m.query(ctx, "pull request id", &query, &query.RateLimit, vars); err != nil {
+		return err
+	}
+
+	var mutation struct {
+		AddComment struct {
+			ClientMutationID githubv4.String
+		} `graphql:"addComment(input:$input)"`
+	}
+
+	input := githubv4.AddCommentInput{
+		SubjectID: query.Repository.PullRequest.ID,
+		Body:      githubv4.String(comment),
+	}
+
+	return m.mutate(ctx, "post comment", &mutation, input)
+}
+
+// CreateCheckRunInput is the input of the createCheckRun mutation, which is missing from githubv4.
+// The type is exported as its name is the GraphQL type of the input.
+type CreateCheckRunInput struct {
+	RepositoryID githubv4.ID          `json:"repositoryId"`
+	HeadSha      githubv4.GitObjectID `json:"headSha"`
+	Name         githubv4.String      `json:"name"`
+	DetailsURL   *githubv4.URI        `json:"detailsUrl,omitempty"`
+	Status       githubv4.String      `json:"status"`
+	Conclusion   *githubv4.String     `json:"conclusion,omitempty"`
+	CompletedAt  *githubv4.DateTime   `json:"completedAt,omitempty"`
+	Output       struct {
+		Title   githubv4.String `json:"title"`
+		Summary githubv4.String `json:"summary"`
+	} `json:"output"`
+}
+
+// createCheckRun reports a commit status as a check run with the V4 API. Check runs can only be
+// created by GitHub Apps, and are named after the context of the status.
+func (m *GithubClient) createCheckRun(ctx context.Context, commitRef, name, status, targetURL, description string) error {
+	detailsURL, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse target url: %s", err)
+	}
+
+	var query struct {
+		Repository struct {
+			ID githubv4.ID
+		} `graphql:"repository(owner:$owner,name:$name)"`
+		RateLimit RateLimitObject
+	}
+
+	vars := map[string]interface{}{
+		"owner": githubv4.String(m.Owner),
+		"name":  githubv4.String(m.Repository),
+	}
+
+	if err := m.query(ctx, "repository id", &query, &query.RateLimit, vars); err != nil {
+		return err
+	}
+
+	input := CreateCheckRunInput{
+		RepositoryID: query.Repository.ID,
+		HeadSha:      githubv4.GitObjectID(commitRef),
+		Name:         githubv4.String(name),
+		Status:       githubv4.String("IN_PROGRESS"),
+	}
+	if detailsURL.IsAbs() {
+		input.DetailsURL = githubv4.NewURI(githubv4.URI{URL: detailsURL})
+	}
+	input.Output.Title = githubv4.String(description)
+	input.Output.Summary = githubv4.String(description)
+
+	// Check runs have no error conclusion, a status of error fails the check run as well
+	switch strings.ToLower(status) {
+	case "success":
+		input.Conclusion = githubv4.NewString("SUCCESS")
+	case "failure", "error":
+		input.Conclusion = githubv4.NewString("FAILURE")
+	}
+	if input.Conclusion != nil {
+		input.Status = githubv4.String("COMPLETED")
+		input.CompletedAt = githubv4.NewDateTime(githubv4.DateTime{Time: time.Now()})
+	}
+
+	var mutation struct {
+		CreateCheckRun struct {
+			ClientMutationID githubv4.String
+		} `graphql:"createCheckRun(input:$input)"`
+	}
+
+	return m.mutate(ctx, "update commit status", &mutation, input)
+}
+
+// commitStatusDetails applies the defaults of the target URL and description of a commit status:
+// the Concourse build, and its status.
+func commitStatusDetails(status, targetURL, description string) (string, string) {
+	if targetURL == "" {
+		targetURL = strings.Join([]string{os.Getenv("ATC_EXTERNAL_URL"), "builds", os.Getenv("BUILD_ID")}, "/")
 	}
-	return parts[0], parts[1], nil
+
+	if description == "" {
+		description = fmt.Sprintf("Concourse CI build %s", status)
+	}
+
+	return targetURL, description
+}
+
+// commitStatusContext joins the base context and context of a commit status, applying the defaults.
+func commitStatusContext(baseContext, context string) string {
+	if baseContext == "" {
+		baseContext = "concourse-ci"
+	}
+
+	if context == "" {
+		context = "status"
+	}
+
+	return path.Join(baseContext, context)
 }
 
 // PullRequestFactory generates a PullRequest object from a PullRequestObject
@@ -344,6 +892,9 @@ func PullRequestFactory(p PullRequestObject) pullrequest.PullRequest {
 		}
 	}
 
+	head := commitFactory(p.HeadRef.Target.CommitObject)
+	head.Statuses = statusesFactory(p.HeadRef.Target.StatusObject)
+
 	return pullrequest.PullRequest{
 		ID:                  p.ID,
 		Number:              p.Number,
@@ -356,7 +907,8 @@ func PullRequestFactory(p PullRequestObject) pullrequest.PullRequest {
 		IsCrossRepository:   p.IsCrossRepository,
 		CreatedAt:           p.CreatedAt.Time,
 		UpdatedAt:           p.UpdatedAt.Time,
-		HeadRef:             commitFactory(p.HeadRef.Target.CommitObject),
+		HeadRef:             head,
+		HeadRefOID:          head.OID,
 		Events:              events,
 		Commits:             commits,
 		Comments:            comments,
@@ -365,6 +917,15 @@ func PullRequestFactory(p PullRequestObject) pullrequest.PullRequest {
 	}
 }
 
+// filesFactory returns the prefetched changed files, and whether they are all of the changed files
+func filesFactory(f PullRequestFilesObject) ([]string, bool) {
+	files := make([]string, 0, len(f.Files.Edges))
+	for _, e := range f.Files.Edges {
+		files = append(files, e.Node.Path)
+	}
+	return files, !f.Files.PageInfo.HasNextPage
+}
+
 func commitFactory(c CommitObject) pullrequest.Commit {
 	return pullrequest.Commit{
 		OID:            c.OID,
@@ -374,7 +935,20 @@ func commitFactory(c CommitObject) pullrequest.Commit {
 		PushedDate:     c.PushedDate.Time,
 		Message:        c.Message,
 		Author:         c.Author.User.Login,
+		Statuses:       make([]pullrequest.Status, 0),
+	}
+}
+
+// statusesFactory returns the commit statuses of a StatusObject
+func statusesFactory(s StatusObject) []pullrequest.Status {
+	statuses := make([]pullrequest.Status, 0, len(s.Status.Contexts))
+	for _, c := range s.Status.Contexts {
+		statuses = append(statuses, pullrequest.Status{
+			Context: c.Context,
+			State:   c.State,
+		})
 	}
+	return statuses
 }
 
 // PreviewSchemaTransport is used to access GraphQL schema's hidden behind an Accept header by GitHub
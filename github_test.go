@@ -1,21 +1,33 @@
 package resource_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	resource "github.com/telia-oss/github-pr-resource"
+	"github.com/telia-oss/github-pr-resource/pullrequest"
 )
 
 func TestNewGithubClient(t *testing.T) {
+	type expect struct {
+		owner      string
+		repository string
+		v3Endpoint string
+	}
+
 	tests := []struct {
 		description string
 		source      resource.Source
-		expect      struct {
-			owner      string
-			repository string
-		}
+		expect      expect
 	}{
 		{
 			description: "owner & repo set properly",
@@ -23,22 +35,570 @@ func TestNewGithubClient(t *testing.T) {
 				Repository:  "itsdalmo/test-repository",
 				AccessToken: "oauthtoken",
 			},
-			expect: struct {
-				owner      string
-				repository string
-			}{
+			expect: expect{
 				owner:      "itsdalmo",
 				repository: "test-repository",
+				v3Endpoint: "https://api.github.com/",
+			},
+		},
+		{
+			description: "github.com repository url",
+			source: resource.Source{
+				Repository:  "https://github.com/itsdalmo/test-repository.git",
+				AccessToken: "oauthtoken",
+			},
+			expect: expect{
+				owner:      "itsdalmo",
+				repository: "test-repository",
+				v3Endpoint: "https://api.github.com/",
+			},
+		},
+		{
+			description: "enterprise repository url",
+			source: resource.Source{
+				Repository:  "https://ghe.example.com/itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+			},
+			expect: expect{
+				owner:      "itsdalmo",
+				repository: "test-repository",
+				v3Endpoint: "https://ghe.example.com/api/v3/",
+			},
+		},
+		{
+			description: "enterprise ssh repository url",
+			source: resource.Source{
+				Repository:  "git@ghe.example.com:itsdalmo/test-repository.git",
+				AccessToken: "oauthtoken",
+			},
+			expect: expect{
+				owner:      "itsdalmo",
+				repository: "test-repository",
+				v3Endpoint: "https://ghe.example.com/api/v3/",
+			},
+		},
+		{
+			description: "enterprise ssh repository url with port",
+			source: resource.Source{
+				Repository:  "ssh://git@ghe.example.com:2222/itsdalmo/test-repository.git",
+				AccessToken: "oauthtoken",
+			},
+			expect: expect{
+				owner:      "itsdalmo",
+				repository: "test-repository",
+				v3Endpoint: "https://ghe.example.com/api/v3/",
+			},
+		},
+		{
+			description: "explicit endpoints win over the repository url",
+			source: resource.Source{
+				Repository:  "https://ghe.example.com/itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  "https://api.ghe.example.com/",
+			},
+			expect: expect{
+				owner:      "itsdalmo",
+				repository: "test-repository",
+				v3Endpoint: "https://api.ghe.example.com/",
 			},
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
+			require.NoError(t, tc.source.Validate())
+
 			client, err := resource.NewGithubClient(&tc.source)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expect.owner, client.Owner)
 			assert.Equal(t, tc.expect.repository, client.Repository)
+			assert.Equal(t, tc.expect.v3Endpoint, client.V3.BaseURL.String())
+		})
+	}
+}
+
+func TestRepositoryURL(t *testing.T) {
+	var requests []graphQLRequest
+	server := newRecordingGraphQLServer(t, map[string]string{
+		"pullRequest(": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"sha1"}}}}}`,
+	}, &requests)
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(&resource.Source{
+		Repository:  server.URL + "/itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+	})
+	require.NoError(t, err)
+
+	_, err = client.GetPullRequest(context.Background(), 1, "")
+	require.NoError(t, err)
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "/api/graphql", requests[0].Path)
+	}
+}
+
+func TestGetPullRequest(t *testing.T) {
+	tests := []struct {
+		description string
+		commit      string
+		responses   map[string]string
+		expectSHA   string
+		orphaned    bool
+		err         bool
+	}{
+		{
+			description: "current head",
+			commit:      "",
+			responses: map[string]string{
+				"commits(last:$last)": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head"}},"commits":{"edges":[{"node":{"commit":{"oid":"head"}}}]}}}}`,
+			},
+			expectSHA: "head",
+		},
+		{
+			description: "commit in the last 100 commits",
+			commit:      "sha1",
+			responses: map[string]string{
+				"commits(last:$last)": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head"}},"commits":{"edges":[{"node":{"commit":{"oid":"sha1"}}},{"node":{"commit":{"oid":"head"}}}]}}}}`,
+			},
+			expectSHA: "sha1",
+		},
+		{
+			description: "commit on a previous page",
+			commit:      "sha0",
+			responses: map[string]string{
+				"commits(last:$last)":         `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head"}},"commits":{"edges":[{"node":{"commit":{"oid":"head"}}}],"pageInfo":{"startCursor":"c1","hasPreviousPage":true}}}}}`,
+				"commits(last:100,before:$c)": `{"repository":{"pullRequest":{"commits":{"edges":[{"node":{"commit":{"oid":"sha0"}}}],"pageInfo":{"startCursor":"c0","hasPreviousPage":false}}}}}`,
+			},
+			expectSHA: "sha0",
+		},
+		{
+			description: "commit no longer part of the pull request",
+			commit:      "forced",
+			responses: map[string]string{
+				"commits(last:$last)": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head"}},"commits":{"edges":[{"node":{"commit":{"oid":"head"}}}]}}}}`,
+				"object(oid:$oid)":    `{"repository":{"object":{"oid":"forced"}}}`,
+			},
+			expectSHA: "forced",
+			orphaned:  true,
+		},
+		{
+			description: "commit does not exist",
+			commit:      "missing",
+			responses: map[string]string{
+				"commits(last:$last)": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head"}},"commits":{"edges":[{"node":{"commit":{"oid":"head"}}}]}}}}`,
+				"object(oid:$oid)":    `{"repository":{"object":null}}`,
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			server := newTestGraphQLServer(t, tc.responses)
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  server.URL,
+				V4Endpoint:  server.URL,
+			})
+			require.NoError(t, err)
+
+			pull, err := client.GetPullRequest(context.Background(), 1, tc.commit)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expectSHA, pull.HeadRef.OID)
+				assert.Equal(t, tc.orphaned, pull.HeadRefOrphaned)
+			}
+		})
+	}
+}
+
+// graphQLRequest is a request received by the test GraphQL server
+type graphQLRequest struct {
+	Path      string                 `json:"-"`
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// newTestGraphQLServer responds with the data of the first response whose key is part of the query,
+// a response starting with `{"data":` is written as is.
+func newTestGraphQLServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return newRecordingGraphQLServer(t, responses, nil)
+}
+
+// newRecordingGraphQLServer is a newTestGraphQLServer which also appends the requests it receives to requests.
+// Mutations are answered with the X-RateLimit headers (4000 remaining), which report their rate limit.
+func newRecordingGraphQLServer(t *testing.T, responses map[string]string, requests *[]graphQLRequest) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := graphQLRequest{Path: r.URL.Path}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %s", err)
+		}
+		if requests != nil {
+			mu.Lock()
+			*requests = append(*requests, request)
+			mu.Unlock()
+		}
+		if strings.HasPrefix(request.Query, "mutation") {
+			w.Header().Set("X-RateLimit-Remaining", "4000")
+			w.Header().Set("X-RateLimit-Reset", "4102444800")
+		}
+
+		for match, data := range responses {
+			if strings.Contains(request.Query, match) {
+				if !strings.HasPrefix(data, `{"data":`) {
+					data = `{"data":` + data + `}`
+				}
+				w.Write([]byte(data))
+				return
+			}
+		}
+
+		t.Errorf("unexpected query: %s", request.Query)
+		http.Error(w, "unexpected query", http.StatusBadRequest)
+	}))
+}
+
+func TestRateLimitFloor(t *testing.T) {
+	server := newTestGraphQLServer(t, map[string]string{
+		"commits(last:$last)":         `{"repository":{"pullRequest":{"number":1,"commits":{"edges":[],"pageInfo":{"startCursor":"c1","hasPreviousPage":true}}}},"rateLimit":{"cost":1,"remaining":5,"resetAt":"2099-01-01T00:00:00Z"}}`,
+		"commits(last:100,before:$c)": `{"repository":{"pullRequest":{"commits":{"edges":[{"node":{"commit":{"oid":"sha0"}}}]}}},"rateLimit":{"cost":1,"remaining":4,"resetAt":"2099-01-01T00:00:00Z"}}`,
+	})
+	defer server.Close()
+
+	tests := []struct {
+		description string
+		floor       int
+		cost        int
+		remaining   int
+		err         bool
+	}{
+		{
+			description: "queries succeed above the floor",
+			floor:       0,
+			cost:        2,
+			remaining:   4,
+		},
+		{
+			description: "queries fail fast below the floor",
+			floor:       10,
+			cost:        1,
+			remaining:   5,
+			err:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:     "itsdalmo/test-repository",
+				AccessToken:    "oauthtoken",
+				V3Endpoint:     server.URL,
+				V4Endpoint:     server.URL,
+				RateLimitFloor: tc.floor,
+			})
+			require.NoError(t, err)
+
+			_, err = client.GetPullRequest(context.Background(), 1, "sha0")
+			if tc.err {
+				assert.Contains(t, fmt.Sprint(err), "rate limit below floor")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.cost, client.Cost)
+			assert.Equal(t, tc.remaining, client.RateLimit.Remaining)
+		})
+	}
+}
+
+func TestRateLimitConcurrentQueries(t *testing.T) {
+	server := newTestGraphQLServer(t, map[string]string{
+		"files(first:100, after: $c)": `{"repository":{"pullRequest":{"files":{"edges":[{"node":{"path":"README.md"}}]}}},"rateLimit":{"cost":1,"remaining":100,"resetAt":"2099-01-01T00:00:00Z"}}`,
+	})
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(&resource.Source{
+		Repository:     "itsdalmo/test-repository",
+		AccessToken:    "oauthtoken",
+		V3Endpoint:     server.URL,
+		V4Endpoint:     server.URL,
+		RateLimitFloor: 10,
+	})
+	require.NoError(t, err)
+
+	// changed files are queried by concurrent workers, which all record the rate limit
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(number int) {
+			defer wg.Done()
+			files, err := client.GetChangedFiles(context.Background(), number)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"README.md"}, files)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8, client.Cost)
+	assert.Equal(t, 100, client.RateLimit.Remaining)
+}
+
+func TestListOpenPullRequests(t *testing.T) {
+	tests := []struct {
+		description string
+		paths       []string
+		response    string
+		expect      []int
+		files       [][]string
+		complete    []bool
+		skipped     int
+		err         bool
+	}{
+		{
+			description: "all pull requests",
+			response:    `{"search":{"edges":[{"node":{"number":1,"headRef":{"target":{"oid":"sha1"}}}},{"node":{"number":2,"headRef":{"target":{"oid":"sha2"}}}}]}}`,
+			expect:      []int{1, 2},
+		},
+		{
+			description: "malformed pull requests are skipped from partial results",
+			response:    `{"data":{"search":{"edges":[{"node":{"number":1,"headRef":{"target":{"oid":"sha1"}}}},{"node":{"number":2,"headRef":null}},{"node":null}]}},"errors":[{"message":"Something went wrong while executing your query."}]}`,
+			expect:      []int{1},
+			skipped:     2,
+		},
+		{
+			description: "first page of changed files is included when paths are configured",
+			paths:       []string{"*.md"},
+			response:    `{"search":{"edges":[{"node":{"number":1,"headRef":{"target":{"oid":"sha1"}},"files":{"edges":[{"node":{"path":"README.md"}}],"pageInfo":{"hasNextPage":false}}}},{"node":{"number":2,"headRef":{"target":{"oid":"sha2"}},"files":{"edges":[{"node":{"path":"a.go"}}],"pageInfo":{"hasNextPage":true}}}}]}}`,
+			expect:      []int{1, 2},
+			files:       [][]string{{"README.md"}, {"a.go"}},
+			complete:    []bool{true, false},
+		},
+		{
+			description: "errors without malformed pull requests fail",
+			response:    `{"data":{"search":{"edges":[{"node":{"number":1,"headRef":{"target":{"oid":"sha1"}}}},{"node":{"number":2,"headRef":{"target":{"oid":"sha2"}}}}]}},"errors":[{"message":"Something went wrong while executing your query."}]}`,
+			err:         true,
+		},
+		{
+			description: "errors without data fail",
+			response:    `{"data":null,"errors":[{"message":"Something went wrong while executing your query."}]}`,
+			err:         true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			match := "search("
+			if len(tc.paths) > 0 {
+				match = "files(first:100)"
+			}
+			server := newTestGraphQLServer(t, map[string]string{match: tc.response})
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  server.URL,
+				V4Endpoint:  server.URL,
+				Paths:       tc.paths,
+			})
+			require.NoError(t, err)
+
+			pulls, err := client.ListOpenPullRequests(context.Background(), time.Now())
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				var numbers []int
+				for i, p := range pulls {
+					numbers = append(numbers, p.Number)
+					if tc.files != nil {
+						assert.Equal(t, tc.files[i], p.Files)
+						assert.Equal(t, tc.complete[i], p.FilesComplete)
+					}
+				}
+				assert.Equal(t, tc.expect, numbers)
+				assert.Equal(t, tc.skipped, client.Skipped)
+			}
+		})
+	}
+}
+
+func TestSearchQueryFields(t *testing.T) {
+	tests := []struct {
+		description string
+		source      resource.Source
+		expected    map[string]interface{}
+	}{
+		{
+			description: "default configuration",
+			source:      resource.Source{},
+			expected:    map[string]interface{}{"withLabels": false, "withReviews": false, "withStatus": false, "withFiles": false},
+		},
+		{
+			description: "labels",
+			source:      resource.Source{Labels: []string{"bug"}},
+			expected:    map[string]interface{}{"withLabels": true, "withReviews": false, "withStatus": false, "withFiles": false},
+		},
+		{
+			description: "required review approvals",
+			source:      resource.Source{RequiredReviewApprovals: 1},
+			expected:    map[string]interface{}{"withLabels": false, "withReviews": true, "withStatus": false, "withFiles": false},
+		},
+		{
+			description: "skip if status",
+			source:      resource.Source{SkipIfStatus: []string{"concourse-ci/status"}},
+			expected:    map[string]interface{}{"withLabels": false, "withReviews": false, "withStatus": true, "withFiles": false},
+		},
+		{
+			description: "paths",
+			source:      resource.Source{Paths: []string{"docs/*"}},
+			expected:    map[string]interface{}{"withLabels": false, "withReviews": false, "withStatus": false, "withFiles": true},
+		},
+		{
+			description: "ignore paths",
+			source:      resource.Source{IgnorePaths: []string{"docs/*"}},
+			expected:    map[string]interface{}{"withLabels": false, "withReviews": false, "withStatus": false, "withFiles": true},
+		},
+		{
+			description: "all options",
+			source: resource.Source{
+				Labels:                  []string{"bug"},
+				RequiredReviewApprovals: 1,
+				SkipIfStatus:            []string{"concourse-ci/status"},
+				Paths:                   []string{"docs/*"},
+			},
+			expected: map[string]interface{}{"withLabels": true, "withReviews": true, "withStatus": true, "withFiles": true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests []graphQLRequest
+			server := newRecordingGraphQLServer(t, map[string]string{"search(": `{"search":{"edges":[]}}`}, &requests)
+			defer server.Close()
+
+			tc.source.Repository = "itsdalmo/test-repository"
+			tc.source.AccessToken = "oauthtoken"
+			tc.source.V3Endpoint = server.URL
+			tc.source.V4Endpoint = server.URL
+
+			client, err := resource.NewGithubClient(&tc.source)
+			require.NoError(t, err)
+
+			_, err = client.ListOpenPullRequests(context.Background(), time.Now())
+			require.NoError(t, err)
+			require.Len(t, requests, 1)
+			query, variables := requests[0].Query, requests[0].Variables
+
+			// The query is the same for every configuration, the fields are included by its variables
+			for _, s := range []string{
+				"labels(first:100) @include(if:$withLabels){",
+				"reviews(states:APPROVED) @include(if:$withReviews){",
+				"... on Commit @include(if:$withStatus){status{contexts{context,state}}}",
+				"... on PullRequest @include(if:$withFiles){files(first:100){",
+			} {
+				assert.Contains(t, query, s)
+			}
+			for name, value := range tc.expected {
+				assert.Equal(t, value, variables[name], name)
+			}
 		})
 	}
 }
+
+func TestCommitStatuses(t *testing.T) {
+	var requests []graphQLRequest
+	server := newRecordingGraphQLServer(t, map[string]string{
+		"object(oid:$oid)": `{"repository":{"object":{"oid":"forced"}}}`,
+		"pullRequest(": `{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"head",` +
+			`"status":{"contexts":[{"context":"concourse-ci/status","state":"SUCCESS"}]}}},` +
+			`"commits":{"edges":[{"node":{"commit":{"oid":"head"}}}]}}}}`,
+	}, &requests)
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(&resource.Source{
+		Repository:   "itsdalmo/test-repository",
+		AccessToken:  "oauthtoken",
+		V3Endpoint:   server.URL,
+		V4Endpoint:   server.URL,
+		SkipIfStatus: []string{"status"},
+	})
+	require.NoError(t, err)
+
+	pull, err := client.GetPullRequest(context.Background(), 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, []pullrequest.Status{{Context: "concourse-ci/status", State: "SUCCESS"}}, pull.HeadRef.Statuses)
+	assert.Equal(t, true, requests[0].Variables["withStatus"])
+
+	// Statuses are only queried for the head commit of the pull request, not for other commits
+	requests = nil
+	_, err = client.GetPullRequest(context.Background(), 1, "forced")
+	require.NoError(t, err)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, 1, strings.Count(requests[0].Query, "status{"))
+		assert.NotContains(t, requests[1].Query, "status{")
+	}
+}
+
+func TestListOpenPullRequestsByRef(t *testing.T) {
+	var requests []graphQLRequest
+	server := newRecordingGraphQLServer(t, map[string]string{
+		"pullRequests(first:100,states:OPEN,baseRefName:$base,headRefName:$head)": `{"repository":{"pullRequests":{"edges":[{"node":{"number":2,"baseRefName":"feature","headRef":{"target":{"oid":"sha2"}}}}]}}}`,
+	}, &requests)
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(&resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL,
+	})
+	require.NoError(t, err)
+
+	pulls, err := client.ListOpenPullRequestsByRef(context.Background(), "feature", "")
+	require.NoError(t, err)
+
+	if assert.Len(t, pulls, 1) {
+		assert.Equal(t, 2, pulls[0].Number)
+		assert.Equal(t, "sha2", pulls[0].HeadRef.OID)
+	}
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "feature", requests[0].Variables["base"])
+		assert.Nil(t, requests[0].Variables["head"])
+	}
+}
+
+func TestListMergeGroups(t *testing.T) {
+	server := newTestGraphQLServer(t, map[string]string{
+		"refs(refPrefix:$prefix,first:100,after:$c)": `{"repository":{"refs":{"edges":[
+			{"node":{"name":"main/pr-12-0a1b2c","target":{"oid":"group12"}}},
+			{"node":{"name":"release/v1/pr-3-3d4e5f","target":{"oid":"group3"}}},
+			{"node":{"name":"unknown","target":{"oid":"sha"}}}
+		]}}}`,
+	})
+	defer server.Close()
+
+	client, err := resource.NewGithubClient(&resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		V3Endpoint:  server.URL,
+		V4Endpoint:  server.URL,
+	})
+	require.NoError(t, err)
+
+	groups, err := client.ListMergeGroups(context.Background())
+	require.NoError(t, err)
+
+	expected := []pullrequest.MergeGroup{
+		{Ref: "gh-readonly-queue/main/pr-12-0a1b2c", Number: 12, BaseRefName: "main", HeadRef: pullrequest.Commit{OID: "group12", Statuses: []pullrequest.Status{}}},
+		{Ref: "gh-readonly-queue/release/v1/pr-3-3d4e5f", Number: 3, BaseRefName: "release/v1", HeadRef: pullrequest.Commit{OID: "group3", Statuses: []pullrequest.Status{}}},
+	}
+	assert.Equal(t, expected, groups)
+}
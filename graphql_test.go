@@ -0,0 +1,124 @@
+package resource_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestGraphQLOnly(t *testing.T) {
+	tests := []struct {
+		description string
+		call        func(*resource.GithubClient) error
+		queries     []string
+		input       map[string]interface{}
+	}{
+		{
+			description: "comments are added with a mutation",
+			call: func(c *resource.GithubClient) error {
+				return c.PostComment(context.Background(), 1, "comment")
+			},
+			queries: []string{"query", "mutation"},
+			input: map[string]interface{}{
+				"subjectId": "PR_1",
+				"body":      "comment",
+			},
+		},
+		{
+			description: "pending statuses are created as check runs in progress",
+			call: func(c *resource.GithubClient) error {
+				return c.UpdateCommitStatus(context.Background(), "sha1", "", "build", "pending", "https://concourse-ci.org", "")
+			},
+			queries: []string{"query", "mutation"},
+			input: map[string]interface{}{
+				"repositoryId": "R_1",
+				"headSha":      "sha1",
+				"name":         "concourse-ci/build",
+				"detailsUrl":   "https://concourse-ci.org",
+				"status":       "IN_PROGRESS",
+				"output":       map[string]interface{}{"title": "Concourse CI build pending", "summary": "Concourse CI build pending"},
+			},
+		},
+		{
+			description: "failed statuses are created as completed check runs",
+			call: func(c *resource.GithubClient) error {
+				return c.UpdateCommitStatus(context.Background(), "sha1", "", "", "error", "https://concourse-ci.org", "Build errored")
+			},
+			queries: []string{"query", "mutation"},
+			input: map[string]interface{}{
+				"repositoryId": "R_1",
+				"headSha":      "sha1",
+				"name":         "concourse-ci/status",
+				"detailsUrl":   "https://concourse-ci.org",
+				"status":       "COMPLETED",
+				"conclusion":   "FAILURE",
+				"output":       map[string]interface{}{"title": "Build errored", "summary": "Build errored"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests []graphQLRequest
+			server := newRecordingGraphQLServer(t, map[string]string{
+				"mutation":                                `{}`,
+				"pullRequest(number:$number){id}":         `{"repository":{"pullRequest":{"id":"PR_1"}}}`,
+				"repository(owner:$owner,name:$name){id}": `{"repository":{"id":"R_1"}}`,
+			}, &requests)
+			defer server.Close()
+
+			source := resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V4Endpoint:  server.URL + "/graphql",
+				GraphQLOnly: true,
+			}
+			require.NoError(t, source.Validate())
+
+			client, err := resource.NewGithubClient(&source)
+			require.NoError(t, err)
+			assert.Nil(t, client.V3)
+
+			require.NoError(t, tc.call(client))
+
+			var queries []string
+			var input map[string]interface{}
+			for _, r := range requests {
+				assert.Equal(t, "/graphql", r.Path)
+				if !strings.HasPrefix(r.Query, "mutation") {
+					queries = append(queries, "query")
+					continue
+				}
+				queries = append(queries, "mutation")
+				input, _ = r.Variables["input"].(map[string]interface{})
+			}
+			if assert.NotNil(t, input) {
+				delete(input, "completedAt")
+			}
+			assert.Equal(t, tc.queries, queries)
+			assert.Equal(t, tc.input, input)
+
+			// The rate limit of the mutation is recorded from the response headers
+			assert.Equal(t, 1, client.Cost)
+			assert.Equal(t, 4000, client.RateLimit.Remaining)
+		})
+	}
+}
+
+func TestGraphQLOnlyPutStatus(t *testing.T) {
+	request := resource.PutRequest{
+		Source: resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+			GraphQLOnly: true,
+		},
+		Params: resource.PutParameters{Status: "success"},
+	}
+
+	_, err := resource.Put(context.Background(), request, nil, "")
+	assert.EqualError(t, err, "invalid parameters: status requires authenticating as a GitHub App (app_id) with graphql_only")
+}
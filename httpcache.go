@@ -0,0 +1,163 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultHTTPCacheSize is the number of responses kept in the http_cache_dir
+const defaultHTTPCacheSize = 1000
+
+// httpCache is a http.RoundTripper which caches the responses to GET requests with an ETag or Last-Modified header,
+// and revalidates them with a conditional request (If-None-Match or If-Modified-Since). A 304 Not Modified response
+// is replaced by the cached response. Responses are cached in memory, and on disk when a directory is configured
+// (e.g. to share them between checks). Only the Gitea client uses it, GitHub is queried with GraphQL.
+type httpCache struct {
+	base    http.RoundTripper
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+	disk    *cacheDir
+}
+
+// cachedResponse is a response as stored in the cache.
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+func newHTTPCache(s *Source, base http.RoundTripper) *httpCache {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	c := &httpCache{base: base, entries: make(map[string]*cachedResponse)}
+	if s.HTTPCacheDir != "" {
+		c.disk = &cacheDir{dir: s.HTTPCacheDir, size: defaultHTTPCacheSize}
+	}
+
+	return c
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *httpCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return c.base.RoundTrip(req)
+	}
+
+	key := httpCacheKey(req)
+	entry := c.get(key)
+
+	r := req
+	if entry != nil {
+		r = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			r.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	res, err := c.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && entry != nil:
+		log.Println("http cache hit:", req.URL.Path)
+		res.Body.Close()
+		return entry.response(req, res), nil
+	case res.StatusCode == http.StatusOK && (res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""):
+		body, err := peekBody(res)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, &cachedResponse{StatusCode: res.StatusCode, Header: res.Header.Clone(), Body: body})
+	}
+
+	return res, nil
+}
+
+func (c *httpCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		return entry
+	}
+	if c.disk == nil {
+		return nil
+	}
+
+	b, ok := c.disk.read(key + ".json")
+	if !ok {
+		return nil
+	}
+
+	var entry cachedResponse
+	if err := json.Unmarshal(b, &entry); err != nil {
+		log.Println("ignoring invalid http cache entry:", key)
+		return nil
+	}
+	c.entries[key] = &entry
+
+	return &entry
+}
+
+func (c *httpCache) put(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+	if c.disk == nil {
+		return
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("failed to cache response:", err)
+		return
+	}
+	if err := c.disk.write(key+".json", b); err != nil {
+		log.Println("failed to cache response:", err)
+	}
+}
+
+// response returns the cached response to the request, with the rate limit reported by the 304 response.
+func (e *cachedResponse) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := e.Header.Clone()
+	for name, values := range notModified.Header {
+		if strings.HasPrefix(name, "X-Ratelimit-") {
+			header[name] = values
+		}
+	}
+	header.Set("X-From-Cache", "1")
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// httpCacheKey identifies the response to a request. The Accept header selects the representation (e.g. a preview).
+func httpCacheKey(req *http.Request) string {
+	hash := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(hash[:])
+}
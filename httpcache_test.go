@@ -0,0 +1,93 @@
+package resource_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestHTTPCache(t *testing.T) {
+	tests := []struct {
+		description string
+		cache       bool
+		disk        bool
+		clients     int
+		expected    []string
+	}{
+		{
+			description: "responses are not cached by default",
+			clients:     1,
+			expected:    []string{"", ""},
+		},
+		{
+			description: "responses are revalidated",
+			cache:       true,
+			clients:     1,
+			expected:    []string{"", `"v1"`},
+		},
+		{
+			description: "responses are cached per invocation",
+			cache:       true,
+			clients:     2,
+			expected:    []string{"", `"v1"`, "", `"v1"`},
+		},
+		{
+			description: "responses are cached on disk",
+			disk:        true,
+			clients:     2,
+			expected:    []string{"", `"v1"`, `"v1"`, `"v1"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var conditions []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/v1/repos/itsdalmo/test-repository", r.URL.Path)
+				conditions = append(conditions, r.Header.Get("If-None-Match"))
+
+				w.Header().Set("X-RateLimit-Remaining", "4999")
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Write([]byte(`{"name":"test-repository"}`))
+			}))
+			defer server.Close()
+
+			dir, err := ioutil.TempDir("", "http-cache")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			source := newGiteaSource(server)
+			source.HTTPCache = tc.cache
+			if tc.disk {
+				source.HTTPCacheDir = dir
+			}
+			require.NoError(t, source.Validate())
+
+			for i := 0; i < tc.clients; i++ {
+				client, err := resource.NewGiteaClient(&source)
+				require.NoError(t, err)
+
+				for j := 0; j < 2; j++ {
+					res, err := client.Client.Get(client.Endpoint + "/repos/itsdalmo/test-repository")
+					if assert.NoError(t, err) {
+						b, _ := ioutil.ReadAll(res.Body)
+						res.Body.Close()
+						assert.Equal(t, `{"name":"test-repository"}`, string(b))
+					}
+				}
+			}
+
+			assert.Equal(t, tc.expected, conditions)
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package resource
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
 
@@ -10,56 +13,88 @@ import (
 )
 
 // Get (business logic)
-func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResponse, error) {
+func Get(ctx context.Context, request GetRequest, github Github, git Git, outputDir string) (*GetResponse, error) {
 	if request.Params.SkipDownload {
 		return &GetResponse{Version: request.Version}, nil
 	}
 
-	pull, err := github.GetPullRequest(request.Version.PR, request.Version.Commit)
+	// The commit of a merge group is not part of the pull request
+	commitRef := request.Version.Commit
+	if request.Version.MergeGroup != "" {
+		commitRef = ""
+	}
+
+	pull, err := github.GetPullRequest(ctx, request.Version.PR, commitRef)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve pull request: %s", err)
 	}
 
+	// A version without a commit uses the current head of the pull request
+	version := request.Version
+	if version.Commit == "" {
+		version.Commit = pull.HeadRef.OID
+		version.UpdatedDate = pull.UpdatedAt
+	}
+
+	// Changed files are cached by the base of the PR, not the base after merging or rebasing
+	baseRefOID := pull.BaseRefOID
+
 	// Initialize and pull the base for the PR
-	err = git.Clone(pull.RepositoryURL, pull.BaseRefName, request.Params.GitDepth)
+	err = git.Clone(ctx, pull.RepositoryURL, pull.BaseRefName, request.Params.GitDepth)
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone repository: %s", err)
 	}
 
 	// Fetch the PR and merge the specified commit into the base
-	if err := git.Fetch(pull.Number, request.Params.GitDepth); err != nil {
+	if err := git.Fetch(ctx, pull.Number, request.Params.GitDepth); err != nil {
 		return nil, err
 	}
 
-	switch request.Params.IntegrationTool {
+	// A commit which is no longer part of the PR is not included in the PR ref
+	if pull.HeadRefOrphaned {
+		if err := git.FetchCommit(ctx, version.Commit, request.Params.GitDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	// The commit of a merge group already contains the PR merged into the base
+	integrationTool := request.Params.IntegrationTool
+	if version.MergeGroup != "" {
+		if err := fetchMergeGroup(ctx, git, version, request.Params.GitDepth); err != nil {
+			return nil, err
+		}
+		integrationTool = "checkout"
+	}
+
+	switch integrationTool {
 	case "rebase":
-		pull.BaseRefOID, err = git.RevParse(pull.BaseRefName)
+		pull.BaseRefOID, err = git.RevParse(ctx, pull.BaseRefName)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := git.Rebase(pull.BaseRefName, request.Version.Commit); err != nil {
+		if err := git.Rebase(ctx, pull.BaseRefName, version.Commit); err != nil {
 			return nil, err
 		}
 	case "merge":
-		pull.BaseRefOID, err = git.RevParse(pull.BaseRefName)
+		pull.BaseRefOID, err = git.RevParse(ctx, pull.BaseRefName)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := git.Merge(request.Version.Commit); err != nil {
+		if err := git.Merge(ctx, version.Commit); err != nil {
 			return nil, err
 		}
 	case "checkout", "":
-		if err := git.Checkout(pull.HeadRefName, request.Version.Commit); err != nil {
+		if err := git.Checkout(ctx, pull.HeadRefName, version.Commit); err != nil {
 			return nil, err
 		}
 	default:
-		return nil, fmt.Errorf("invalid integration tool specified: %s", request.Params.IntegrationTool)
+		return nil, fmt.Errorf("invalid integration tool specified: %s", integrationTool)
 	}
 
 	if request.Source.GitCryptKey != "" {
-		if err := git.GitCryptUnlock(request.Source.GitCryptKey); err != nil {
+		if err := git.GitCryptUnlock(ctx, request.Source.GitCryptKey); err != nil {
 			return nil, err
 		}
 	}
@@ -70,7 +105,18 @@ func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResp
 	}
 
 	metadata := metadataFactory(pull)
-	metadata.AddJSON("version", &request.Version)
+	metadata.AddJSON("version", &version)
+	if version.MergeGroup != "" {
+		metadata.Add("merge_group", version.MergeGroup)
+	}
+
+	if request.Source.StackedPullRequests {
+		stack, err := pullRequestStack(ctx, pull, github)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull request stack: %s", err)
+		}
+		metadata.AddJSON("stack", &stack)
+	}
 
 	b, err := metadata.JSON()
 	if err != nil {
@@ -83,12 +129,24 @@ func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResp
 
 	metadata.ToFiles(path)
 
-	if request.Params.ListChangedFiles {
-		cfol, err := github.GetChangedFiles(request.Version.PR)
+	var cfol []string
+	if request.Params.ListChangedFiles || len(request.Source.Projects) > 0 {
+		if request.Params.LocalChangedFiles {
+			cfol, err = localChangedFiles(ctx, git, pull.BaseRefName, version.Commit)
+		} else {
+			// The changed files are those of the current head (HeadRef is the requested commit), so they are only cached when the two match
+			cache := newFilesCache(request.Source)
+			if version.Commit != pull.HeadRefOID {
+				cache = nil
+			}
+			cfol, err = cachedChangedFiles(ctx, pull.Number, pull.HeadRefOID, baseRefOID, github, cache)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to fetch list of changed files: %s", err)
 		}
+	}
 
+	if request.Params.ListChangedFiles {
 		var fl []byte
 
 		for _, v := range cfol {
@@ -101,12 +159,42 @@ func Get(request GetRequest, github Github, git Git, outputDir string) (*GetResp
 		}
 	}
 
+	if len(request.Source.Projects) > 0 {
+		b, err := json.Marshal(affectedProjects(request.Source.Projects, cfol))
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile("affected_projects", path, b); err != nil {
+			return nil, err
+		}
+	}
+
 	return &GetResponse{
-		Version:  request.Version,
+		Version:  version,
 		Metadata: metadata,
 	}, nil
 }
 
+// fetchMergeGroup fetches the branch of a merge group, or its commit once the branch is deleted (e.g. after merging).
+func fetchMergeGroup(ctx context.Context, git Git, version Version, depth int) error {
+	err := git.FetchRef(ctx, version.MergeGroup, depth)
+	if err == nil {
+		return nil
+	}
+
+	log.Println("merge group not found, fetching commit:", err)
+	return git.FetchCommit(ctx, version.Commit, depth)
+}
+
+// localChangedFiles lists the files changed by the head commit since it diverged from the base branch.
+func localChangedFiles(ctx context.Context, git Git, baseRefName, head string) ([]string, error) {
+	base, err := git.MergeBase(ctx, "origin/"+baseRefName, head)
+	if err != nil {
+		return nil, err
+	}
+	return git.ChangedFiles(ctx, base, head)
+}
+
 func writeFile(name, path string, b []byte) error {
 	if err := ioutil.WriteFile(filepath.Join(path, name+".json"), b, 0644); err != nil {
 		return fmt.Errorf("failed to write %s: %s", name, err)
@@ -124,6 +212,8 @@ type GetParameters struct {
 	GitDepth int `json:"git_depth"`
 	// ListChangedFiles generates a list of changed files in the `.git` directory
 	ListChangedFiles bool `json:"list_changed_files"`
+	// LocalChangedFiles computes the list of changed files with git instead of the API
+	LocalChangedFiles bool `json:"local_changed_files"`
 }
 
 // GetRequest ...
@@ -1,12 +1,17 @@
 package resource_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	resource "github.com/telia-oss/github-pr-resource"
 	"github.com/telia-oss/github-pr-resource/fakes"
 	"github.com/telia-oss/github-pr-resource/pullrequest"
@@ -38,7 +43,7 @@ func TestGet(t *testing.T) {
 			parameters:     resource.GetParameters{},
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 		},
 		{
 			description: "get supports unlocking with git crypt",
@@ -55,7 +60,7 @@ func TestGet(t *testing.T) {
 			parameters:     resource.GetParameters{},
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 		},
 		{
 			description: "get supports rebasing",
@@ -73,7 +78,7 @@ func TestGet(t *testing.T) {
 			},
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 		},
 		{
 			description: "get supports merge",
@@ -91,7 +96,7 @@ func TestGet(t *testing.T) {
 			},
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 		},
 		{
 			description: "get supports git_depth",
@@ -109,7 +114,7 @@ func TestGet(t *testing.T) {
 			},
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 		},
 		{
 			description: "get supports list_changed_files",
@@ -128,7 +133,7 @@ func TestGet(t *testing.T) {
 			pullRequest:    createTestPR(1, "master", false, false, false, false, 0, nil),
 			files:          []string{"README.md", "Other.md"},
 			versionString:  `{"pr":"1","commit":"commit1","updated":"0001-01-01T00:00:00Z"}`,
-			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
+			metadataString: `[{"name":"pr","value":"1"},{"name":"url","value":"pr1 url"},{"name":"head_name","value":"pr1"},{"name":"head_sha","value":"oid1"},{"name":"head_short_sha","value":"oid1"},{"name":"base_name","value":"master"},{"name":"base_sha","value":"sha"},{"name":"message","value":"commit message1"},{"name":"author","value":"login1"},{"name":"commit_in_pr","value":"true"},{"name":"events","value":"[]"},{"name":"labels","value":"null"},{"name":"version","value":"{\"pr\":\"1\",\"commit\":\"commit1\",\"updated\":\"0001-01-01T00:00:00Z\"}"}]`,
 			filesString:    "README.md\nOther.md\n",
 		},
 	}
@@ -149,7 +154,7 @@ func TestGet(t *testing.T) {
 			defer os.RemoveAll(dir)
 
 			input := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.parameters}
-			output, err := resource.Get(input, github, git, dir)
+			output, err := resource.Get(context.Background(), input, github, git, dir)
 
 			// Validate output
 			if assert.NoError(t, err) {
@@ -173,6 +178,7 @@ func TestGet(t *testing.T) {
 					"base_sha":       "sha",
 					"message":        "commit message1",
 					"author":         "login1",
+					"commit_in_pr":   "true",
 				}
 
 				for filename, expected := range files {
@@ -188,21 +194,21 @@ func TestGet(t *testing.T) {
 
 			// Validate Github calls
 			if assert.Equal(t, 1, github.GetPullRequestCallCount()) {
-				pr, commit := github.GetPullRequestArgsForCall(0)
+				_, pr, commit := github.GetPullRequestArgsForCall(0)
 				assert.Equal(t, tc.version.PR, pr)
 				assert.Equal(t, tc.version.Commit, commit)
 			}
 
 			// Validate Git calls
 			if assert.Equal(t, 1, git.CloneCallCount()) {
-				url, base, depth := git.CloneArgsForCall(0)
+				_, url, base, depth := git.CloneArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.RepositoryURL, url)
 				assert.Equal(t, tc.pullRequest.BaseRefName, base)
 				assert.Equal(t, tc.parameters.GitDepth, depth)
 			}
 
 			if assert.Equal(t, 1, git.FetchCallCount()) {
-				pr, depth := git.FetchArgsForCall(0)
+				_, pr, depth := git.FetchArgsForCall(0)
 				assert.Equal(t, tc.pullRequest.Number, pr)
 				assert.Equal(t, tc.parameters.GitDepth, depth)
 			}
@@ -210,36 +216,36 @@ func TestGet(t *testing.T) {
 			switch tc.parameters.IntegrationTool {
 			case "rebase":
 				if assert.Equal(t, 1, git.RevParseCallCount()) {
-					base := git.RevParseArgsForCall(0)
+					_, base := git.RevParseArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.BaseRefName, base)
 				}
 
 				if assert.Equal(t, 1, git.RebaseCallCount()) {
-					branch, tip := git.RebaseArgsForCall(0)
+					_, branch, tip := git.RebaseArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.BaseRefName, branch)
 					assert.Equal(t, tc.version.Commit, tip)
 				}
 			case "checkout", "":
 				if assert.Equal(t, 1, git.CheckoutCallCount()) {
-					branch, sha := git.CheckoutArgsForCall(0)
+					_, branch, sha := git.CheckoutArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.HeadRefName, branch)
 					assert.Equal(t, tc.version.Commit, sha)
 				}
 			case "merge":
 				if assert.Equal(t, 1, git.RevParseCallCount()) {
-					base := git.RevParseArgsForCall(0)
+					_, base := git.RevParseArgsForCall(0)
 					assert.Equal(t, tc.pullRequest.BaseRefName, base)
 				}
 
 				if assert.Equal(t, 1, git.MergeCallCount()) {
-					tip := git.MergeArgsForCall(0)
+					_, tip := git.MergeArgsForCall(0)
 					assert.Equal(t, tc.version.Commit, tip)
 				}
 			}
 
 			if tc.source.GitCryptKey != "" {
 				if assert.Equal(t, 1, git.GitCryptUnlockCallCount()) {
-					key := git.GitCryptUnlockArgsForCall(0)
+					_, key := git.GitCryptUnlockArgsForCall(0)
 					assert.Equal(t, tc.source.GitCryptKey, key)
 				}
 			}
@@ -247,6 +253,318 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetPullRequestNumber(t *testing.T) {
+	github := new(fakes.FakeGithub)
+	pull := createTestPR(1, "master", false, false, false, false, 0, nil)
+	github.GetPullRequestReturns(pull, nil)
+
+	git := new(fakes.FakeGit)
+	dir := createTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	input := resource.GetRequest{
+		Source: resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+		},
+		Version: resource.Version{PR: 1},
+	}
+	output, err := resource.Get(context.Background(), input, github, git, dir)
+
+	if assert.NoError(t, err) {
+		expected := resource.Version{PR: 1, Commit: "oid1", UpdatedDate: pull.UpdatedAt}
+		assert.Equal(t, expected, output.Version)
+
+		b, err := json.Marshal(&expected)
+		require.NoError(t, err)
+		version := readTestFile(t, filepath.Join(dir, ".git", "resource", "version.json"))
+		assert.Equal(t, string(b), version)
+	}
+
+	if assert.Equal(t, 1, github.GetPullRequestCallCount()) {
+		_, _, commit := github.GetPullRequestArgsForCall(0)
+		assert.Equal(t, "", commit)
+	}
+
+	if assert.Equal(t, 1, git.CheckoutCallCount()) {
+		_, _, sha := git.CheckoutArgsForCall(0)
+		assert.Equal(t, "oid1", sha)
+	}
+}
+
+func TestGetOrphanedCommit(t *testing.T) {
+	github := new(fakes.FakeGithub)
+	pull := createTestPR(1, "master", false, false, false, false, 0, nil)
+	pull.HeadRefOrphaned = true
+	github.GetPullRequestReturns(pull, nil)
+
+	git := new(fakes.FakeGit)
+	dir := createTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	input := resource.GetRequest{
+		Source: resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+		},
+		Version: resource.Version{PR: 1, Commit: "commit1"},
+		Params:  resource.GetParameters{GitDepth: 1},
+	}
+	_, err := resource.Get(context.Background(), input, github, git, dir)
+
+	if assert.NoError(t, err) {
+		inPR := readTestFile(t, filepath.Join(dir, ".git", "resource", "commit_in_pr"))
+		assert.Equal(t, "false", inPR)
+	}
+
+	if assert.Equal(t, 1, git.FetchCommitCallCount()) {
+		_, sha, depth := git.FetchCommitArgsForCall(0)
+		assert.Equal(t, "commit1", sha)
+		assert.Equal(t, 1, depth)
+	}
+}
+
+func TestGetLocalChangedFiles(t *testing.T) {
+	github := new(fakes.FakeGithub)
+	pull := createTestPR(1, "master", false, false, false, false, 0, nil)
+	github.GetPullRequestReturns(pull, nil)
+
+	git := new(fakes.FakeGit)
+	git.MergeBaseReturns("base1", nil)
+	git.ChangedFilesReturns([]string{"README.md", "docs/old.md", "docs/new.md"}, nil)
+	dir := createTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	input := resource.GetRequest{
+		Source: resource.Source{
+			Repository:  "itsdalmo/test-repository",
+			AccessToken: "oauthtoken",
+		},
+		Version: resource.Version{PR: 1, Commit: "commit1"},
+		Params:  resource.GetParameters{ListChangedFiles: true, LocalChangedFiles: true},
+	}
+	_, err := resource.Get(context.Background(), input, github, git, dir)
+
+	if assert.NoError(t, err) {
+		files := readTestFile(t, filepath.Join(dir, ".git", "resource", "changed_files"))
+		assert.Equal(t, "README.md\ndocs/old.md\ndocs/new.md\n", files)
+	}
+
+	if assert.Equal(t, 1, git.MergeBaseCallCount()) {
+		_, base, head := git.MergeBaseArgsForCall(0)
+		assert.Equal(t, "origin/master", base)
+		assert.Equal(t, "commit1", head)
+	}
+	if assert.Equal(t, 1, git.ChangedFilesCallCount()) {
+		_, base, head := git.ChangedFilesArgsForCall(0)
+		assert.Equal(t, "base1", base)
+		assert.Equal(t, "commit1", head)
+	}
+	assert.Equal(t, 0, github.GetChangedFilesCallCount())
+}
+
+func TestGetChangedFilesCache(t *testing.T) {
+	github := new(fakes.FakeGithub)
+	// The clients return the requested commit as HeadRef, along with the OID of the current head
+	github.GetPullRequestStub = func(_ context.Context, _ int, commitRef string) (pullrequest.PullRequest, error) {
+		pull := createTestPR(1, "master", false, false, false, false, 0, nil)
+		if commitRef != "" {
+			pull.HeadRef.OID = commitRef
+		}
+		return pull, nil
+	}
+	github.GetChangedFilesReturns([]string{"README.md"}, nil)
+
+	cache, err := ioutil.TempDir("", "changed-files-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cache)
+
+	get := func(commit string) {
+		dir := createTestDirectory(t)
+		defer os.RemoveAll(dir)
+
+		input := resource.GetRequest{
+			Source: resource.Source{
+				Repository:           "itsdalmo/test-repository",
+				AccessToken:          "oauthtoken",
+				ChangedFilesCacheDir: cache,
+			},
+			Version: resource.Version{PR: 1, Commit: commit},
+			Params:  resource.GetParameters{ListChangedFiles: true},
+		}
+		_, err := resource.Get(context.Background(), input, github, new(fakes.FakeGit), dir)
+		require.NoError(t, err)
+	}
+
+	// the changed files of an older commit are those of the head, which are not cached for either commit
+	get("commit0")
+	entries, err := ioutil.ReadDir(cache)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	get("oid1")
+	get("oid1")
+	assert.Equal(t, 2, github.GetChangedFilesCallCount())
+
+	entries, err = ioutil.ReadDir(cache)
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "itsdalmo-test-repository-1-oid1-sha.json", entries[0].Name())
+	}
+}
+
+func TestGetAffectedProjects(t *testing.T) {
+	tests := []struct {
+		description string
+		files       []string
+		expected    string
+	}{
+		{
+			description: "no affected projects",
+			files:       []string{"README.md"},
+			expected:    `[]`,
+		},
+		{
+			description: "directly affected project",
+			files:       []string{"web/index.html"},
+			expected:    `["web"]`,
+		},
+		{
+			description: "dependents of an affected project",
+			files:       []string{"lib/util.go"},
+			expected:    `["api","web","lib"]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.GetPullRequestReturns(createTestPR(1, "master", false, false, false, false, 0, nil), nil)
+			github.GetChangedFilesReturns(tc.files, nil)
+
+			git := new(fakes.FakeGit)
+			dir := createTestDirectory(t)
+			defer os.RemoveAll(dir)
+
+			input := resource.GetRequest{
+				Source: resource.Source{
+					Repository:  "itsdalmo/test-repository",
+					AccessToken: "oauthtoken",
+					Projects: []resource.Project{
+						{Name: "api", Paths: []string{"api/*"}, DependsOn: []string{"lib"}},
+						{Name: "web", Paths: []string{"web/*"}, DependsOn: []string{"api"}},
+						{Name: "lib", Paths: []string{"lib/*"}},
+					},
+				},
+				Version: resource.Version{PR: 1, Commit: "oid1"},
+			}
+			_, err := resource.Get(context.Background(), input, github, git, dir)
+
+			if assert.NoError(t, err) {
+				projects := readTestFile(t, filepath.Join(dir, ".git", "resource", "affected_projects.json"))
+				assert.Equal(t, tc.expected, projects)
+			}
+		})
+	}
+}
+
+func TestGetStackedPullRequest(t *testing.T) {
+	pulls := map[string]pullrequest.PullRequest{
+		"pr1": createTestPR(1, "master", false, false, false, false, 0, nil),
+		"pr2": createTestPR(2, "pr1", false, false, false, false, 0, nil),
+	}
+
+	github := new(fakes.FakeGithub)
+	github.GetPullRequestReturns(createTestPR(3, "pr2", false, false, false, false, 0, nil), nil)
+	github.ListOpenPullRequestsByRefStub = func(_ context.Context, base, head string) ([]pullrequest.PullRequest, error) {
+		if p, ok := pulls[head]; ok {
+			return []pullrequest.PullRequest{p}, nil
+		}
+		return nil, nil
+	}
+
+	git := new(fakes.FakeGit)
+	dir := createTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	input := resource.GetRequest{
+		Source: resource.Source{
+			Repository:          "itsdalmo/test-repository",
+			AccessToken:         "oauthtoken",
+			StackedPullRequests: true,
+		},
+		Version: resource.Version{PR: 3, Commit: "oid3"},
+	}
+	_, err := resource.Get(context.Background(), input, github, git, dir)
+
+	if assert.NoError(t, err) {
+		stack := readTestFile(t, filepath.Join(dir, ".git", "resource", "stack.json"))
+		assert.Equal(t, "[1,2,3]", stack)
+	}
+	assert.Equal(t, 3, github.ListOpenPullRequestsByRefCallCount())
+}
+
+func TestGetMergeGroup(t *testing.T) {
+	tests := []struct {
+		description string
+		fetchErr    error
+		fetchCommit int
+	}{
+		{
+			description: "merge group ref is checked out",
+		},
+		{
+			description: "merge group commit is fetched once the ref is deleted",
+			fetchErr:    errors.New("fetch ref failed"),
+			fetchCommit: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			github := new(fakes.FakeGithub)
+			github.GetPullRequestReturns(createTestPR(1, "master", false, false, false, false, 0, nil), nil)
+
+			git := new(fakes.FakeGit)
+			git.FetchRefReturns(tc.fetchErr)
+			dir := createTestDirectory(t)
+			defer os.RemoveAll(dir)
+
+			input := resource.GetRequest{
+				Source: resource.Source{
+					Repository:  "itsdalmo/test-repository",
+					AccessToken: "oauthtoken",
+					MergeQueue:  true,
+				},
+				Version: resource.Version{PR: 1, Commit: "group1", MergeGroup: "gh-readonly-queue/master/pr-1-sha"},
+				Params:  resource.GetParameters{IntegrationTool: "merge"},
+			}
+			output, err := resource.Get(context.Background(), input, github, git, dir)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, input.Version, output.Version)
+				group := readTestFile(t, filepath.Join(dir, ".git", "resource", "merge_group"))
+				assert.Equal(t, "gh-readonly-queue/master/pr-1-sha", group)
+			}
+
+			if assert.Equal(t, 1, github.GetPullRequestCallCount()) {
+				_, _, commit := github.GetPullRequestArgsForCall(0)
+				assert.Equal(t, "", commit)
+			}
+			if assert.Equal(t, 1, git.FetchRefCallCount()) {
+				_, ref, _ := git.FetchRefArgsForCall(0)
+				assert.Equal(t, "gh-readonly-queue/master/pr-1-sha", ref)
+			}
+			assert.Equal(t, tc.fetchCommit, git.FetchCommitCallCount())
+			assert.Equal(t, 0, git.MergeCallCount())
+			if assert.Equal(t, 1, git.CheckoutCallCount()) {
+				_, _, sha := git.CheckoutArgsForCall(0)
+				assert.Equal(t, "group1", sha)
+			}
+		})
+	}
+}
+
 func TestGetSkipDownload(t *testing.T) {
 
 	tests := []struct {
@@ -279,7 +597,7 @@ func TestGetSkipDownload(t *testing.T) {
 
 			// Run the get and check output
 			input := resource.GetRequest{Source: tc.source, Version: tc.version, Params: tc.parameters}
-			output, err := resource.Get(input, github, git, dir)
+			output, err := resource.Get(context.Background(), input, github, git, dir)
 
 			if assert.NoError(t, err) {
 				assert.Equal(t, tc.version, output.Version)
@@ -1,9 +1,11 @@
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"time"
 )
@@ -43,12 +45,43 @@ func WriteStdin() []byte {
 	}
 
 	if debug {
-		Write(string(input))
+		Write(redact(input))
 	}
 
 	return input
 }
 
+// redact removes the credentials of the proxy from the request, which is otherwise logged as is.
+func redact(input []byte) string {
+	var request map[string]interface{}
+	if err := json.Unmarshal(input, &request); err != nil {
+		return string(input)
+	}
+
+	source, ok := request["source"].(map[string]interface{})
+	if !ok {
+		return string(input)
+	}
+	proxy, ok := source["proxy"].(string)
+	if !ok {
+		return string(input)
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		// The proxy is invalid, but may still contain credentials
+		source["proxy"] = "xxxxx"
+	} else {
+		source["proxy"] = u.Redacted()
+	}
+
+	b, err := json.Marshal(request)
+	if err != nil {
+		return string(input)
+	}
+	return string(b)
+}
+
 // Write writes a message to a log file in `/tmp`
 func Write(msg string) {
 	log.Println(msg)
@@ -20,6 +20,7 @@ func metadataFactory(pull pullrequest.PullRequest) meta.Metadata {
 	m.Add("base_sha", pull.BaseRefOID)
 	m.Add("message", pull.HeadRef.Message)
 	m.Add("author", pull.HeadRef.Author)
+	m.Add("commit_in_pr", strconv.FormatBool(!pull.HeadRefOrphaned))
 	m.Add("events", fmt.Sprintf("%v", pull.Events))
 
 	m.AddJSON("labels", &pull.Labels)
@@ -3,6 +3,7 @@ package resource
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -14,20 +15,57 @@ import (
 type Source struct {
 	// Repository to check, get, put
 	Repository string `json:"repository"`
+	// Provider hosting the Repository: github (default) or gitea
+	Provider string `json:"provider,omitempty"`
 	// AccessToken for GitHub API with permissions to Repository
 	AccessToken string `json:"access_token"`
+	// AccessTokens is a pool of tokens used instead of AccessToken, by remaining rate limit
+	AccessTokens []string `json:"access_tokens,omitempty"`
+	// AppID, InstallationID & PrivateKey authenticate as a GitHub App installation instead of with AccessToken
+	AppID          int64  `json:"app_id,omitempty"`
+	InstallationID int64  `json:"installation_id,omitempty"`
+	PrivateKey     string `json:"private_key,omitempty"`
 	// V3Endpoint for GitHub Rest API (leave blank for cloud)
 	V3Endpoint string `json:"v3_endpoint"`
 	// V4Endpoint for GitHub GraphQL API (leave blank for cloud)
 	V4Endpoint string `json:"v4_endpoint"`
+	// GraphQLOnly sends all requests through the V4 API, commit statuses are created as check runs
+	GraphQLOnly bool `json:"graphql_only,omitempty"`
 	// Paths of Repository to return versions for
 	Paths []string `json:"paths,omitempty"`
 	// IgnorePaths of Repository to skip returning versions for
 	IgnorePaths []string `json:"ignore_paths,omitempty"`
+	// ChangedFilesConcurrency limits the concurrent changed files lookups for Paths & IgnorePaths
+	ChangedFilesConcurrency int `json:"changed_files_concurrency,omitempty"`
+	// ChangedFilesCacheDir enables caching the changed files of pull requests on disk
+	ChangedFilesCacheDir string `json:"changed_files_cache_dir,omitempty"`
+	// ChangedFilesCacheSize limits the number of cached changed files lists
+	ChangedFilesCacheSize int `json:"changed_files_cache_size,omitempty"`
+	// HTTPCache revalidates the cached responses of Gitea API requests with conditional requests
+	HTTPCache bool `json:"http_cache,omitempty"`
+	// HTTPCacheDir additionally stores the cached responses on disk, e.g. to share them between checks
+	HTTPCacheDir string `json:"http_cache_dir,omitempty"`
+	// Projects of a monorepo which are detected as affected by the changed files
+	Projects []Project `json:"projects,omitempty"`
+	// SkipUnaffectedProjects skips versions which do not affect any of the Projects
+	SkipUnaffectedProjects bool `json:"skip_unaffected_projects,omitempty"`
+	// StackedPullRequests returns versions for PRs stacked on another PR when the head of that PR changes
+	StackedPullRequests bool `json:"stacked_pull_requests,omitempty"`
+	// MergeQueue returns versions for the merge groups of the merge queue instead of pull requests
+	MergeQueue bool `json:"merge_queue,omitempty"`
 	// DisableCISkip disables ability to skip CI via PR title / message
 	DisableCISkip bool `json:"disable_ci_skip,omitempty"`
 	// SkipSSLVerification when executing GitHub API requests
 	SkipSSLVerification bool `json:"skip_ssl_verification,omitempty"`
+	// CACerts (PEM) trusted in addition to the system roots, e.g. for a self-signed GitHub Enterprise certificate
+	CACerts string `json:"ca_certs,omitempty"`
+	// ClientCert & ClientKey (PEM) presented to GitHub Enterprise when it requires a client certificate
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// Proxy (URL, optionally with credentials) for API and git requests, instead of the proxy environment variables
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy lists the hosts which are reached without the Proxy, comma separated as in NO_PROXY
+	NoProxy string `json:"no_proxy,omitempty"`
 	// DisableForks disables versions from forks of Repository
 	DisableForks bool `json:"disable_forks,omitempty"`
 	// GitCryptKey enables GitCrypt unlocking
@@ -40,26 +78,221 @@ type Source struct {
 	RequiredReviewApprovals int `json:"required_review_approvals,omitempty"`
 	// Labels returns versions for PRs matching labels
 	Labels []string `json:"labels,omitempty"`
+	// SkipIfStatus skips versions whose head commit already has a terminal status for a base_context/context
+	SkipIfStatus []string `json:"skip_if_status,omitempty"`
+	// InitialLookback limits the search when there is no previous version (defaults to 3 years), unless initial_versions is none
+	InitialLookback Duration `json:"initial_lookback,omitempty"`
+	// InitialVersions returned when there is no previous version: latest (default), all or none (only new updates)
+	InitialVersions string `json:"initial_versions,omitempty"`
+	// SearchOverlap searches for updates this long before the last version, e.g. to allow for search index lag
+	SearchOverlap Duration `json:"search_overlap,omitempty"`
+	// RateLimitFloor fails fast when the remaining GraphQL rate limit drops below it
+	RateLimitFloor int `json:"rate_limit_floor,omitempty"`
+	// Retries of failed API requests (defaults to 3, -1 disables retries)
+	Retries int `json:"retries,omitempty"`
+	// RetryWait before the first retry, doubled for each retry
+	RetryWait Duration `json:"retry_wait,omitempty"`
+	// RetryTimeout after which an API request is no longer retried
+	RetryTimeout Duration `json:"retry_timeout,omitempty"`
+	// RequestTimeout of each API request, including retries (defaults to 5m)
+	RequestTimeout Duration `json:"request_timeout,omitempty"`
+	// GitTimeout of each git operation, e.g. a clone or fetch (defaults to 15m)
+	GitTimeout Duration `json:"git_timeout,omitempty"`
 }
 
 // Validate the source configuration.
 func (s *Source) Validate() error {
-	if s.AccessToken == "" || s.Repository == "" {
+	if s.appAuthentication() {
+		if s.AccessToken != "" || len(s.AccessTokens) > 0 {
+			return errors.New("access_token must not be set when authenticating as a GitHub App")
+		}
+	} else if s.AppID != 0 || s.InstallationID != 0 || s.PrivateKey != "" {
+		return errors.New("app_id, installation_id & private_key are required to authenticate as a GitHub App")
+	} else if s.AccessToken != "" && len(s.AccessTokens) > 0 {
+		return errors.New("access_token & access_tokens are mutually exclusive")
+	} else if s.AccessToken == "" && len(s.AccessTokens) == 0 {
 		return errors.New("access_token & repository are required")
 	}
 
-	if len(s.V3Endpoint)+len(s.V4Endpoint) > 0 && (s.V3Endpoint == "" || s.V4Endpoint == "") {
-		return errors.New("both v3_endpoint & v4_endpoint endpoints are required for GitHub Enterprise")
+	for _, t := range s.AccessTokens {
+		if t == "" {
+			return errors.New("access_tokens must not be empty")
+		}
+	}
+
+	if s.Repository == "" {
+		return errors.New("access_token & repository are required")
+	}
+
+	if (s.ClientCert == "") != (s.ClientKey == "") {
+		return errors.New("client_cert & client_key are required together")
+	}
+
+	if s.Proxy != "" {
+		if _, err := parseProxy(s.Proxy); err != nil {
+			return err
+		}
+	} else if s.NoProxy != "" {
+		return errors.New("no_proxy requires proxy")
+	}
+
+	if _, err := parseRepository(s.Repository); err != nil {
+		return fmt.Errorf("invalid repository: %s", err)
+	}
+
+	switch v3, v4 := s.endpoints(); s.Provider {
+	case "", ProviderGithub:
+		// Conditional requests are only supported by the REST API, which is not used to query pull requests
+		if s.HTTPCache || s.HTTPCacheDir != "" {
+			return errors.New("http_cache & http_cache_dir are only supported by gitea")
+		}
+		if !s.GraphQLOnly && len(v3)+len(v4) > 0 && (v3 == "" || v4 == "") {
+			return errors.New("both v3_endpoint & v4_endpoint endpoints are required for GitHub Enterprise")
+		}
+		if s.GraphQLOnly && v3 != "" && v4 == "" {
+			return errors.New("v4_endpoint is required for GitHub Enterprise")
+		}
+		// Installation tokens are only issued by the V3 API
+		if s.GraphQLOnly && s.appAuthentication() && v4 != "" && v3 == "" {
+			return errors.New("v3_endpoint is required to authenticate as a GitHub App on GitHub Enterprise")
+		}
+		// Statuses are created as check runs, which neither skip_if_status nor the required status checks of a
+		// merge queue match
+		if s.GraphQLOnly && (len(s.SkipIfStatus) > 0 || s.MergeQueue) {
+			return errors.New("skip_if_status & merge_queue are not supported with graphql_only")
+		}
+	case ProviderGitea:
+		if v3 == "" {
+			return errors.New("v3_endpoint or a repository URL is required for gitea")
+		}
+		if s.appAuthentication() || s.MergeQueue || s.GraphQLOnly {
+			return errors.New("app_id, merge_queue & graphql_only are not supported by gitea")
+		}
+	default:
+		return fmt.Errorf("unknown provider: %s", s.Provider)
+	}
+
+	switch s.InitialVersions {
+	case "", InitialVersionsLatest, InitialVersionsAll, InitialVersionsNone:
+	default:
+		return fmt.Errorf("unknown initial_versions: %s", s.InitialVersions)
+	}
+
+	if s.InitialLookback < 0 {
+		return errors.New("initial_lookback must not be negative")
+	}
+
+	if s.ChangedFilesConcurrency < 0 {
+		return errors.New("changed_files_concurrency must not be negative")
+	}
+
+	if s.ChangedFilesCacheSize < 0 {
+		return errors.New("changed_files_cache_size must not be negative")
+	}
+
+	if err := validateProjects(s.Projects); err != nil {
+		return err
+	}
+
+	if s.SkipUnaffectedProjects && len(s.Projects) == 0 {
+		return errors.New("skip_unaffected_projects requires projects")
+	}
+
+	if s.SearchOverlap < 0 {
+		return errors.New("search_overlap must not be negative")
+	}
+
+	if s.RateLimitFloor < 0 {
+		return errors.New("rate_limit_floor must not be negative")
+	}
+
+	if s.Retries < -1 {
+		return errors.New("retries must be -1 (disabled) or more")
+	}
+
+	if s.RetryWait < 0 || s.RetryTimeout < 0 {
+		return errors.New("retry_wait & retry_timeout must not be negative")
+	}
+
+	if s.RequestTimeout < 0 || s.GitTimeout < 0 {
+		return errors.New("request_timeout & git_timeout must not be negative")
 	}
 
 	return nil
 }
 
+// appAuthentication returns true if the source authenticates as a GitHub App installation.
+func (s *Source) appAuthentication() bool {
+	return s.AppID != 0 && s.InstallationID != 0 && s.PrivateKey != ""
+}
+
+// accessToken returns the access_token, or the first of the access_tokens.
+func (s *Source) accessToken() string {
+	if s.AccessToken == "" && len(s.AccessTokens) > 0 {
+		return s.AccessTokens[0]
+	}
+	return s.AccessToken
+}
+
+// requestTimeout returns the request_timeout, or the default.
+func (s *Source) requestTimeout() time.Duration {
+	if s.RequestTimeout == 0 {
+		return defaultRequestTimeout
+	}
+	return time.Duration(s.RequestTimeout)
+}
+
+// gitTimeout returns the git_timeout, or the default.
+func (s *Source) gitTimeout() time.Duration {
+	if s.GitTimeout == 0 {
+		return defaultGitTimeout
+	}
+	return time.Duration(s.GitTimeout)
+}
+
+// Provider options
+const (
+	ProviderGithub = "github"
+	ProviderGitea  = "gitea"
+)
+
+// InitialVersions options
+const (
+	InitialVersionsLatest = "latest"
+	InitialVersionsAll    = "all"
+	InitialVersionsNone   = "none"
+)
+
+// Duration is a time.Duration which is configured as a duration string, e.g. "72h".
+type Duration time.Duration
+
+// MarshalJSON custom marshaller to convert the duration to a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON custom unmarshaller to parse the duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
 // Version communicated with Concourse.
 type Version struct {
 	PR          int       `json:"pr"`
 	Commit      string    `json:"commit"`
 	UpdatedDate time.Time `json:"updated"`
+	MergeGroup  string    `json:"merge_group,omitempty"`
 }
 
 // MarshalJSON custom marshaller to convert PR number
@@ -126,6 +359,7 @@ type PullRequestObject struct {
 		Name   string
 		Target struct {
 			CommitObject `graphql:"... on Commit"`
+			StatusObject `graphql:"... on Commit @include(if:$withStatus)"`
 		}
 	}
 	Repository struct {
@@ -137,10 +371,10 @@ type PullRequestObject struct {
 				LabelObject
 			}
 		}
-	} `graphql:"labels(first:100)"`
+	} `graphql:"labels(first:100) @include(if:$withLabels)"`
 	Reviews struct {
 		TotalCount int
-	} `graphql:"reviews(states:APPROVED)"`
+	} `graphql:"reviews(states:APPROVED) @include(if:$withReviews)"`
 	TimelineItems struct {
 		Edges []struct {
 			Node struct {
@@ -192,12 +426,46 @@ type CommitObject struct {
 	}
 }
 
+// StatusObject represents the GraphQL status of a commit, which is only queried for the head commit.
+// https://developer.github.com/v4/object/status/
+type StatusObject struct {
+	Status struct {
+		Contexts []struct {
+			Context string
+			State   string
+		}
+	}
+}
+
 // ChangedFileObject represents the GraphQL FilesChanged node.
 // https://developer.github.com/v4/object/pullrequestchangedfile/
 type ChangedFileObject struct {
 	Path string
 }
 
+// RateLimitObject represents the GraphQL rate limit node.
+// https://developer.github.com/v4/object/ratelimit/
+type RateLimitObject struct {
+	Cost      int
+	Remaining int
+	ResetAt   githubv4.DateTime
+}
+
+// PullRequestFilesObject represents the first page of the GraphQL files connection of a pull request.
+// https://developer.github.com/v4/object/pullrequestchangedfileconnection/
+type PullRequestFilesObject struct {
+	Files struct {
+		Edges []struct {
+			Node struct {
+				ChangedFileObject
+			}
+		}
+		PageInfo struct {
+			HasNextPage bool
+		}
+	} `graphql:"files(first:100)"`
+}
+
 // LabelObject represents the GraphQL label node.
 // https://developer.github.com/v4/object/label
 type LabelObject struct {
@@ -1,7 +1,9 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,11 +14,15 @@ import (
 )
 
 // Put (business logic)
-func Put(request PutRequest, manager Github, inputDir string) (*PutResponse, error) {
+func Put(ctx context.Context, request PutRequest, manager Github, inputDir string) (*PutResponse, error) {
 	err := request.Params.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("invalid parameters: %s", err)
 	}
+	// Commit statuses are created as check runs with graphql_only, which only GitHub Apps can create
+	if request.Params.Status != "" && request.Source.GraphQLOnly && !request.Source.appAuthentication() {
+		return nil, errors.New("invalid parameters: status requires authenticating as a GitHub App (app_id) with graphql_only")
+	}
 	path := filepath.Join(inputDir, request.Params.Path, ".git", "resource")
 
 	// Version available after a GET step.
@@ -43,14 +49,14 @@ func Put(request PutRequest, manager Github, inputDir string) (*PutResponse, err
 
 	// Set status if specified
 	if p := request.Params; p.Status != "" {
-		if err := manager.UpdateCommitStatus(version.Commit, p.BaseContext, os.ExpandEnv(p.Context), p.Status, os.ExpandEnv(p.TargetURL), p.Description); err != nil {
+		if err := manager.UpdateCommitStatus(ctx, version.Commit, p.BaseContext, os.ExpandEnv(p.Context), p.Status, os.ExpandEnv(p.TargetURL), p.Description); err != nil {
 			return nil, fmt.Errorf("failed to set status: %s", err)
 		}
 	}
 
 	// Set comment if specified
 	if p := request.Params; p.Comment != "" {
-		err = manager.PostComment(version.PR, os.ExpandEnv(p.Comment))
+		err = manager.PostComment(ctx, version.PR, os.ExpandEnv(p.Comment))
 		if err != nil {
 			return nil, fmt.Errorf("failed to post comment: %s", err)
 		}
@@ -64,7 +70,7 @@ func Put(request PutRequest, manager Github, inputDir string) (*PutResponse, err
 		}
 		comment := string(content)
 		if comment != "" {
-			err = manager.PostComment(version.PR, os.ExpandEnv(comment))
+			err = manager.PostComment(ctx, version.PR, os.ExpandEnv(comment))
 			if err != nil {
 				return nil, fmt.Errorf("failed to post comment: %s", err)
 			}
@@ -1,6 +1,7 @@
 package resource_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -127,6 +128,25 @@ func TestPut(t *testing.T) {
 			pullRequest: createTestPR(1, "master", false, false, false, false, 0, nil),
 		},
 
+		{
+			description: "we can set status on the commit of a merge group",
+			source: resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				MergeQueue:  true,
+			},
+			version: resource.Version{
+				PR:          1,
+				Commit:      "group1",
+				UpdatedDate: time.Time{},
+				MergeGroup:  "gh-readonly-queue/master/pr-1-sha",
+			},
+			parameters: resource.PutParameters{
+				Status: "success",
+			},
+			pullRequest: createTestPR(1, "master", false, false, false, false, 0, nil),
+		},
+
 		{
 			description: "we can comment on the pull request",
 			source: resource.Source{
@@ -159,11 +179,11 @@ func TestPut(t *testing.T) {
 			// Run get so we have version and metadata for the put request
 			// (This is tested in in_test.go)
 			getInput := resource.GetRequest{Source: tc.source, Version: tc.version, Params: resource.GetParameters{}}
-			_, err := resource.Get(getInput, github, git, dir)
+			_, err := resource.Get(context.Background(), getInput, github, git, dir)
 			require.NoError(t, err)
 
 			putInput := resource.PutRequest{Source: tc.source, Params: tc.parameters}
-			output, err := resource.Put(putInput, github, dir)
+			output, err := resource.Put(context.Background(), putInput, github, dir)
 
 			// Validate output
 			if assert.NoError(t, err) {
@@ -173,7 +193,7 @@ func TestPut(t *testing.T) {
 			// Validate method calls put on Github.
 			if tc.parameters.Status != "" {
 				if assert.Equal(t, 1, github.UpdateCommitStatusCallCount()) {
-					commit, baseContext, context, status, targetURL, description := github.UpdateCommitStatusArgsForCall(0)
+					_, commit, baseContext, context, status, targetURL, description := github.UpdateCommitStatusArgsForCall(0)
 					assert.Equal(t, tc.version.Commit, commit)
 					assert.Equal(t, tc.parameters.BaseContext, baseContext)
 					assert.Equal(t, tc.parameters.Context, context)
@@ -184,7 +204,7 @@ func TestPut(t *testing.T) {
 			}
 			if tc.parameters.Comment != "" {
 				if assert.Equal(t, 1, github.PostCommentCallCount()) {
-					pr, comment := github.PostCommentArgsForCall(0)
+					_, pr, comment := github.PostCommentArgsForCall(0)
 					assert.Equal(t, tc.version.PR, pr)
 					assert.Equal(t, tc.parameters.Comment, comment)
 				}
@@ -262,7 +282,7 @@ func TestVariableSubstitution(t *testing.T) {
 
 			// Run get so we have version and metadata for the put request
 			getInput := resource.GetRequest{Source: tc.source, Version: tc.version, Params: resource.GetParameters{}}
-			_, err := resource.Get(getInput, github, git, dir)
+			_, err := resource.Get(context.Background(), getInput, github, git, dir)
 			require.NoError(t, err)
 
 			oldValue := os.Getenv(variableName)
@@ -271,18 +291,18 @@ func TestVariableSubstitution(t *testing.T) {
 			os.Setenv(variableName, variableValue)
 
 			putInput := resource.PutRequest{Source: tc.source, Params: tc.parameters}
-			_, err = resource.Put(putInput, github, dir)
+			_, err = resource.Put(context.Background(), putInput, github, dir)
 
 			if tc.parameters.TargetURL != "" {
 				if assert.Equal(t, 1, github.UpdateCommitStatusCallCount()) {
-					_, _, _, _, targetURL, _ := github.UpdateCommitStatusArgsForCall(0)
+					_, _, _, _, _, targetURL, _ := github.UpdateCommitStatusArgsForCall(0)
 					assert.Equal(t, tc.expectedTargetURL, targetURL)
 				}
 			}
 
 			if tc.parameters.Comment != "" {
 				if assert.Equal(t, 1, github.PostCommentCallCount()) {
-					_, comment := github.PostCommentArgsForCall(0)
+					_, _, comment := github.PostCommentArgsForCall(0)
 					assert.Equal(t, tc.expectedComment, comment)
 				}
 			}
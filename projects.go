@@ -0,0 +1,110 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/telia-oss/github-pr-resource/pullrequest"
+)
+
+// Project is a named part of a monorepo. A project is affected by a pull request when any of the changed files
+// match its paths, or when any of the projects it depends on are affected.
+type Project struct {
+	Name      string   `json:"name"`
+	Paths     []string `json:"paths"`
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// validateProjects checks that project names are unique, dependencies exist and do not form a cycle.
+func validateProjects(projects []Project) error {
+	index := make(map[string]Project, len(projects))
+	for _, p := range projects {
+		if p.Name == "" {
+			return fmt.Errorf("projects require a name")
+		}
+		if _, ok := index[p.Name]; ok {
+			return fmt.Errorf("duplicate project: %s", p.Name)
+		}
+		if len(p.Paths) == 0 {
+			return fmt.Errorf("project %s requires paths", p.Name)
+		}
+		index[p.Name] = p
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(projects))
+
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		chain = append(chain, name)
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("circular project dependency: %s", strings.Join(chain, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, d := range index[name].DependsOn {
+			if _, ok := index[d]; !ok {
+				return fmt.Errorf("project %s depends on unknown project: %s", name, d)
+			}
+			if err := visit(d, chain); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, p := range projects {
+		if err := visit(p.Name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// affectedProjects returns the names of the projects affected by the changed files, in the configured order.
+// The projects are expected to be validated, i.e. free of unknown and circular dependencies.
+func affectedProjects(projects []Project, files []string) []string {
+	index := make(map[string]Project, len(projects))
+	for _, p := range projects {
+		index[p.Name] = p
+	}
+
+	pull := pullrequest.PullRequest{Files: files}
+	affected := make(map[string]bool, len(projects))
+
+	var isAffected func(p Project) bool
+	isAffected = func(p Project) bool {
+		if v, ok := affected[p.Name]; ok {
+			return v
+		}
+
+		v := pullrequest.Files(p.Paths, false)(pull)
+		for _, d := range p.DependsOn {
+			if v {
+				break
+			}
+			v = isAffected(index[d])
+		}
+		affected[p.Name] = v
+
+		return v
+	}
+
+	names := []string{}
+	for _, p := range projects {
+		if isAffected(p) {
+			names = append(names, p.Name)
+		}
+	}
+
+	return names
+}
@@ -3,6 +3,7 @@ package pullrequest
 import (
 	"log"
 	"regexp"
+	"strings"
 	"time"
 
 	glob "github.com/sabhiram/go-gitignore"
@@ -100,6 +101,26 @@ func Labels(v []string) Filter {
 	}
 }
 
+// TerminalStatus returns true if the HeadRef already has a terminal (success, failure, error) status for any of the contexts
+func TerminalStatus(contexts []string) Filter {
+	return func(p PullRequest) bool {
+		for _, c := range contexts {
+			for _, s := range p.HeadRef.Statuses {
+				if c != s.Context {
+					continue
+				}
+
+				switch strings.ToUpper(s.State) {
+				case "SUCCESS", "FAILURE", "ERROR":
+					log.Println("terminal status: true -", s.Context, s.State)
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
 // Created returns true if the PR was created with no new commits or since the last check
 func Created(v time.Time) Filter {
 	return func(p PullRequest) bool {
@@ -224,6 +224,73 @@ func TestLabels(t *testing.T) {
 	}
 }
 
+func TestTerminalStatus(t *testing.T) {
+	tests := []struct {
+		description string
+		contexts    []string
+		pull        pullrequest.PullRequest
+		expect      bool
+	}{
+		{
+			description: "no contexts configured",
+			contexts:    []string{},
+			pull: pullrequest.PullRequest{
+				HeadRef: pullrequest.Commit{
+					Statuses: []pullrequest.Status{{Context: "concourse-ci/status", State: "SUCCESS"}},
+				},
+			},
+			expect: false,
+		},
+		{
+			description: "match success",
+			contexts:    []string{"concourse-ci/status"},
+			pull: pullrequest.PullRequest{
+				HeadRef: pullrequest.Commit{
+					Statuses: []pullrequest.Status{{Context: "concourse-ci/status", State: "SUCCESS"}},
+				},
+			},
+			expect: true,
+		},
+		{
+			description: "match failure",
+			contexts:    []string{"concourse-ci/unit-test", "concourse-ci/status"},
+			pull: pullrequest.PullRequest{
+				HeadRef: pullrequest.Commit{
+					Statuses: []pullrequest.Status{{Context: "concourse-ci/status", State: "FAILURE"}},
+				},
+			},
+			expect: true,
+		},
+		{
+			description: "no match pending",
+			contexts:    []string{"concourse-ci/status"},
+			pull: pullrequest.PullRequest{
+				HeadRef: pullrequest.Commit{
+					Statuses: []pullrequest.Status{{Context: "concourse-ci/status", State: "PENDING"}},
+				},
+			},
+			expect: false,
+		},
+		{
+			description: "no match other context",
+			contexts:    []string{"concourse-ci/status"},
+			pull: pullrequest.PullRequest{
+				HeadRef: pullrequest.Commit{
+					Statuses: []pullrequest.Status{{Context: "other-ci/status", State: "SUCCESS"}},
+				},
+			},
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			out := pullrequest.TerminalStatus(tc.contexts)(tc.pull)
+			assert.Equal(t, tc.expect, out)
+		})
+	}
+}
+
 func TestRequiredApprovals(t *testing.T) {
 	tests := []struct {
 		description string
@@ -16,14 +16,25 @@ type PullRequest struct {
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
 	HeadRef             Commit
+	HeadRefOID          string
+	HeadRefOrphaned     bool
 	Events              []Event
 	Comments            []Comment
 	Commits             []Commit
 	Files               []string
+	FilesComplete       bool
 	Labels              []string
 	ApprovedReviewCount int
 }
 
+// MergeGroup represents an entry of a merge queue: the PR merged into its base, along with the entries ahead of it
+type MergeGroup struct {
+	Ref         string
+	Number      int
+	BaseRefName string
+	HeadRef     Commit
+}
+
 // Commit represents a commit
 type Commit struct {
 	OID            string
@@ -33,6 +44,13 @@ type Commit struct {
 	PushedDate     time.Time
 	Message        string
 	Author         string
+	Statuses       []Status
+}
+
+// Status represents a commit status reported for a context
+type Status struct {
+	Context string
+	State   string
 }
 
 // Event represents an event that has been recorded on the PR
@@ -0,0 +1,87 @@
+package resource
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// repository is the owner and name of a repository, and the GitHub instance it is on when given as a URL.
+type repository struct {
+	owner  string
+	name   string
+	scheme string
+	host   string
+}
+
+// scpRepository matches the scp-like syntax of git for ssh URLs, e.g. git@ghe.example.com:owner/name.git
+var scpRepository = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+):([^/].*)$`)
+
+// parseRepository parses a repository given as owner/name, or as the https or ssh URL of the repository.
+func parseRepository(s string) (repository, error) {
+	var r repository
+
+	path := s
+	switch {
+	case strings.Contains(s, "://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return repository{}, errors.New("malformed repository")
+		}
+		switch u.Scheme {
+		case "http", "https":
+			r.scheme, r.host = u.Scheme, u.Host
+		case "ssh", "git":
+			// The API of an instance reached with ssh is served over https
+			r.scheme, r.host = "https", u.Hostname()
+		default:
+			return repository{}, errors.New("malformed repository: unsupported scheme")
+		}
+		path = u.Path
+	case scpRepository.MatchString(s):
+		match := scpRepository.FindStringSubmatch(s)
+		r.scheme, r.host = "https", match[1]
+		path = match[2]
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return repository{}, errors.New("malformed repository")
+	}
+	r.owner, r.name = parts[0], parts[1]
+
+	return r, nil
+}
+
+// endpoints returns the v3_endpoint and v4_endpoint, which are derived from the repository URL
+// for GitHub Enterprise unless they are configured. Empty endpoints are those of github.com.
+// Gitea only has a REST API (/api/v1), which takes the place of the v3_endpoint.
+func (s *Source) endpoints() (string, string) {
+	v3, v4 := s.V3Endpoint, s.V4Endpoint
+
+	r, err := parseRepository(s.Repository)
+	if err != nil || r.host == "" {
+		return v3, v4
+	}
+
+	base := r.scheme + "://" + r.host
+	switch {
+	case s.Provider == ProviderGitea:
+		if v3 == "" {
+			v3 = base + "/api/v1/"
+		}
+		return v3, v4
+	case r.host == "github.com":
+		return v3, v4
+	}
+
+	if v3 == "" {
+		v3 = base + "/api/v3/"
+	}
+	if v4 == "" {
+		v4 = base + "/api/graphql"
+	}
+
+	return v3, v4
+}
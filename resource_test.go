@@ -43,7 +43,7 @@ func createTestPR(count int, baseName string, skipCI, isCrossRepo, created, noco
 		commit.ID = ""
 	}
 
-	pr := resource.PullRequestFactory(resource.PullRequestObject{
+	p := resource.PullRequestObject{
 		ID:                fmt.Sprintf("pr%s", n),
 		Number:            count,
 		Title:             fmt.Sprintf("pr%s title", n),
@@ -54,23 +54,15 @@ func createTestPR(count int, baseName string, skipCI, isCrossRepo, created, noco
 		IsCrossRepository: isCrossRepo,
 		CreatedAt:         githubv4.DateTime{Time: c},
 		UpdatedAt:         githubv4.DateTime{Time: u},
-		HeadRef: struct {
-			ID     string
-			Name   string
-			Target struct {
-				resource.CommitObject `graphql:"... on Commit"`
-			}
-		}{
-			ID:   fmt.Sprintf("commit%s", n),
-			Name: fmt.Sprintf("pr%s", n),
-			Target: struct {
-				resource.CommitObject `graphql:"... on Commit"`
-			}{commit},
-		},
 		Repository: struct{ URL string }{
 			URL: fmt.Sprintf("repo%s url", n),
 		},
-	})
+	}
+	p.HeadRef.ID = fmt.Sprintf("commit%s", n)
+	p.HeadRef.Name = fmt.Sprintf("pr%s", n)
+	p.HeadRef.Target.CommitObject = commit
+
+	pr := resource.PullRequestFactory(p)
 
 	pr.ApprovedReviewCount = approvedReviews
 	pr.Labels = labels
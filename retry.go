@@ -0,0 +1,206 @@
+package resource
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRetries is the number of times a request is retried, unless configured
+	defaultRetries = 3
+	// defaultRetryWait is the backoff before the first retry, unless configured
+	defaultRetryWait = time.Second
+	// defaultRetryTimeout is the time after which a request is no longer retried, unless configured
+	defaultRetryTimeout = 2 * time.Minute
+)
+
+// retryTransport is a http.RoundTripper which retries failed requests with exponential backoff and jitter.
+// Rate limited requests are retried once the rate limit resets (Retry-After or X-RateLimit-Reset), as they are
+// rejected without being processed. Server errors are only retried for idempotent requests, so e.g. a comment
+// is never posted twice.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	wait    time.Duration
+	timeout time.Duration
+}
+
+func newRetryTransport(s *Source, base http.RoundTripper) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &retryTransport{
+		base:    base,
+		retries: s.Retries,
+		wait:    time.Duration(s.RetryWait),
+		timeout: time.Duration(s.RetryTimeout),
+	}
+	switch {
+	case t.retries < 0:
+		t.retries = 0
+	case t.retries == 0:
+		t.retries = defaultRetries
+	}
+	if t.wait == 0 {
+		t.wait = defaultRetryWait
+	}
+	if t.timeout == 0 {
+		t.timeout = defaultRetryTimeout
+	}
+
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent, err := idempotentRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(t.timeout)
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		res, err := t.base.RoundTrip(r)
+
+		wait, reason := t.retry(res, err, idempotent, attempt)
+		if reason == "" || attempt >= t.retries || time.Now().Add(wait).After(deadline) ||
+			(req.Body != nil && req.GetBody == nil) {
+			return res, err
+		}
+
+		log.Printf("retrying %s %s in %s (%d/%d): %s\n", req.Method, req.URL.Path, wait, attempt+1, t.retries, reason)
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retry returns how long to wait before retrying and the reason for retrying, or an empty reason to not retry.
+func (t *retryTransport) retry(res *http.Response, err error, idempotent bool, attempt int) (time.Duration, string) {
+	// double the wait for each attempt, without exceeding the timeout (or overflowing for large numbers of retries)
+	backoff := t.wait
+	for i := 0; i < attempt && backoff < t.timeout; i++ {
+		backoff *= 2
+	}
+	if backoff > t.timeout || backoff <= 0 {
+		backoff = t.timeout
+	}
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	if err != nil {
+		if !idempotent {
+			return 0, ""
+		}
+		return backoff, err.Error()
+	}
+
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if !rateLimited(res) {
+			return 0, ""
+		}
+		if wait, ok := rateLimitWait(res); ok {
+			return wait, res.Status
+		}
+		return backoff, res.Status
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotent {
+			return 0, ""
+		}
+		if wait, ok := rateLimitWait(res); ok {
+			return wait, res.Status
+		}
+		return backoff, res.Status
+	}
+
+	return 0, ""
+}
+
+// rateLimited returns true if a 403 or 429 response is caused by a (primary or secondary) rate limit.
+func rateLimited(res *http.Response) bool {
+	if res.Header.Get("X-RateLimit-Remaining") == "0" || res.Header.Get("Retry-After") != "" {
+		return true
+	}
+
+	body, err := peekBody(res)
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(body), []byte("rate limit"))
+}
+
+// rateLimitWait returns the time until a request may be retried, according to the response headers.
+func rateLimitWait(res *http.Response) (time.Duration, bool) {
+	if v := res.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(v); err == nil {
+			return time.Until(date), true
+		}
+	}
+
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// allow for clock skew
+			return time.Until(time.Unix(reset, 0)) + time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+// idempotentRequest returns true if the request can safely be repeated, e.g. GraphQL queries (but not mutations)
+// and commit statuses (where the latest status for a context wins).
+func idempotentRequest(req *http.Request) (bool, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true, nil
+	case http.MethodPost:
+		if strings.Contains(req.URL.Path, "/statuses/") {
+			return true, nil
+		}
+		if strings.HasSuffix(req.URL.Path, "/graphql") && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return false, err
+			}
+			defer body.Close()
+
+			b, err := ioutil.ReadAll(body)
+			if err != nil {
+				return false, err
+			}
+			return !bytes.HasPrefix(b, []byte(`{"query":"mutation`)), nil
+		}
+	}
+
+	return false, nil
+}
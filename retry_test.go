@@ -0,0 +1,144 @@
+package resource_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestRetries(t *testing.T) {
+	secondaryRateLimit := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+	}
+	badGateway := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	tests := []struct {
+		description string
+		retries     int
+		call        func(*resource.GithubClient) error
+		failures    []func(http.ResponseWriter)
+		requests    int
+		err         bool
+	}{
+		{
+			description: "queries are retried on server errors",
+			call:        getTestPullRequest,
+			failures:    []func(http.ResponseWriter){badGateway, badGateway},
+			requests:    3,
+		},
+		{
+			description: "queries are not retried more than configured",
+			retries:     1,
+			call:        getTestPullRequest,
+			failures:    []func(http.ResponseWriter){badGateway, badGateway},
+			requests:    2,
+			err:         true,
+		},
+		{
+			description: "retries can be disabled",
+			retries:     -1,
+			call:        getTestPullRequest,
+			failures:    []func(http.ResponseWriter){badGateway},
+			requests:    1,
+			err:         true,
+		},
+		{
+			description: "statuses are retried on server errors",
+			call: func(c *resource.GithubClient) error {
+				return c.UpdateCommitStatus(context.Background(), "sha1", "", "", "success", "", "")
+			},
+			failures: []func(http.ResponseWriter){badGateway},
+			requests: 2,
+		},
+		{
+			description: "comments are not retried on server errors",
+			call: func(c *resource.GithubClient) error {
+				return c.PostComment(context.Background(), 1, "comment")
+			},
+			failures: []func(http.ResponseWriter){badGateway},
+			requests: 1,
+			err:      true,
+		},
+		{
+			description: "comments are retried when rate limited",
+			call: func(c *resource.GithubClient) error {
+				return c.PostComment(context.Background(), 1, "comment")
+			},
+			failures: []func(http.ResponseWriter){secondaryRateLimit},
+			requests: 2,
+		},
+		{
+			description: "backoff does not overflow for large numbers of retries",
+			retries:     64,
+			call:        getTestPullRequest,
+			failures:    repeat(secondaryRateLimit, 64),
+			requests:    65,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				if r.Method == http.MethodPost {
+					assert.NotEmpty(t, body, "request body should be replayed")
+				}
+
+				if requests <= len(tc.failures) {
+					tc.failures[requests-1](w)
+					return
+				}
+
+				switch r.URL.Path {
+				case "/graphql":
+					w.Write([]byte(`{"data":{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"sha1"}}}}}}`))
+				default:
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{}`))
+				}
+			}))
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:  "itsdalmo/test-repository",
+				AccessToken: "oauthtoken",
+				V3Endpoint:  server.URL + "/",
+				V4Endpoint:  server.URL + "/graphql",
+				Retries:     tc.retries,
+				RetryWait:   resource.Duration(time.Millisecond),
+			})
+			require.NoError(t, err)
+
+			err = tc.call(client)
+			assert.Equal(t, tc.err, err != nil, "unexpected error: %v", err)
+			assert.Equal(t, tc.requests, requests)
+		})
+	}
+}
+
+func repeat(f func(http.ResponseWriter), n int) []func(http.ResponseWriter) {
+	fs := make([]func(http.ResponseWriter), n)
+	for i := range fs {
+		fs[i] = f
+	}
+	return fs
+}
+
+func getTestPullRequest(c *resource.GithubClient) error {
+	_, err := c.GetPullRequest(context.Background(), 1, "")
+	return err
+}
@@ -0,0 +1,129 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/telia-oss/github-pr-resource/pullrequest"
+)
+
+// stackedPulls returns the PRs stacked on candidates whose head changed, i.e. the open PRs with their head ref as base ref.
+// The head of a stacked PR is unchanged, so terminal statuses do not exclude it and it is dated to the candidate.
+func stackedPulls(ctx context.Context, r CheckRequest, candidates []pullrequest.PullRequest, since time.Time, manager Github) ([]pullrequest.PullRequest, error) {
+	var dependents []pullrequest.PullRequest
+	for _, p := range candidates {
+		if p.IsCrossRepository || !headChanged(p, since) {
+			continue
+		}
+
+		pulls, err := manager.ListOpenPullRequestsByRef(ctx, p.HeadRefName, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stacked pull requests: %s", err)
+		}
+
+		for _, d := range pulls {
+			if excluded(r, d) {
+				continue
+			}
+			log.Println("stacked pull request:", d.Number, "on:", p.Number)
+
+			if p.UpdatedAt.After(d.UpdatedAt) {
+				d.UpdatedAt = p.UpdatedAt
+			}
+			dependents = append(dependents, d)
+		}
+	}
+
+	return dependents, nil
+}
+
+// stackOrder moves the versions of stacked PRs after the version of the PR they are stacked on,
+// otherwise keeping the order of the response.
+func stackOrder(r CheckResponse, pulls []pullrequest.PullRequest) CheckResponse {
+	heads := make(map[string]int, len(pulls))
+	for _, p := range pulls {
+		if !p.IsCrossRepository {
+			heads[p.HeadRefName] = p.Number
+		}
+	}
+
+	parents := make(map[int]int, len(pulls))
+	for _, p := range pulls {
+		if n, ok := heads[p.BaseRefName]; ok && n != p.Number {
+			parents[p.Number] = n
+		}
+	}
+
+	versions := make(map[int]bool, len(r))
+	for _, v := range r {
+		versions[v.PR] = true
+	}
+
+	var (
+		ordered = make(CheckResponse, 0, len(r))
+		placed  = make([]bool, len(r))
+		pr      = make(map[int]bool, len(r))
+		pending = make(map[int][]int)
+	)
+
+	var place func(i int)
+	place = func(i int) {
+		ordered = append(ordered, r[i])
+		placed[i] = true
+		pr[r[i].PR] = true
+
+		dependents := pending[r[i].PR]
+		delete(pending, r[i].PR)
+		for _, d := range dependents {
+			place(d)
+		}
+	}
+
+	for i, v := range r {
+		if n, ok := parents[v.PR]; ok && versions[n] && !pr[n] {
+			pending[n] = append(pending[n], i)
+			continue
+		}
+		place(i)
+	}
+
+	// PRs stacked on each other in a cycle are left in order
+	for i, v := range r {
+		if !placed[i] {
+			ordered = append(ordered, v)
+		}
+	}
+
+	return ordered
+}
+
+// pullRequestStack returns the numbers of the open PRs the PR is stacked on, from the bottom of the stack up to and including the PR.
+func pullRequestStack(ctx context.Context, pull pullrequest.PullRequest, manager Github) ([]int, error) {
+	stack := []int{pull.Number}
+	seen := map[int]bool{pull.Number: true}
+
+	base := pull.BaseRefName
+	for {
+		pulls, err := manager.ListOpenPullRequestsByRef(ctx, "", base)
+		if err != nil {
+			return nil, err
+		}
+
+		var parent *pullrequest.PullRequest
+		for i, p := range pulls {
+			if !p.IsCrossRepository && !seen[p.Number] {
+				parent = &pulls[i]
+				break
+			}
+		}
+		if parent == nil {
+			return stack, nil
+		}
+
+		stack = append([]int{parent.Number}, stack...)
+		seen[parent.Number] = true
+		base = parent.BaseRefName
+	}
+}
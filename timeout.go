@@ -0,0 +1,63 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultRequestTimeout is the timeout of a GitHub API request (including retries), unless configured
+	defaultRequestTimeout = 5 * time.Minute
+	// defaultGitTimeout is the timeout of a git operation (e.g. clone), unless configured
+	defaultGitTimeout = 15 * time.Minute
+)
+
+// SignalContext returns a context which is cancelled on SIGTERM (e.g. when Concourse aborts the build) or SIGINT,
+// so that running API requests and git commands are stopped.
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		select {
+		case s := <-signals:
+			log.Println("received signal, cancelling:", s)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
+
+// withTimeout returns a context which is cancelled after the timeout, or when the parent is cancelled.
+// A timeout of 0 only inherits the deadline of the parent.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutError replaces the error of an operation which timed out or was cancelled (e.g. on SIGTERM) with
+// one naming the operation, as the underlying error (e.g. "signal: killed") rarely says why it failed.
+func timeoutError(ctx context.Context, operation string, timeout time.Duration, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%s timed out after %s", operation, timeout)
+	case context.Canceled:
+		return fmt.Errorf("%s cancelled", operation)
+	}
+	return err
+}
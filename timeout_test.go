@@ -0,0 +1,122 @@
+package resource_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+func TestGithubTimeout(t *testing.T) {
+	tests := []struct {
+		description string
+		cancel      bool
+		call        func(context.Context, *resource.GithubClient) error
+		expected    string
+	}{
+		{
+			description: "queries time out",
+			call: func(ctx context.Context, c *resource.GithubClient) error {
+				_, err := c.GetPullRequest(ctx, 1, "")
+				return err
+			},
+			expected: "pull request query timed out after 50ms",
+		},
+		{
+			description: "rest requests time out",
+			call: func(ctx context.Context, c *resource.GithubClient) error {
+				return c.PostComment(ctx, 1, "comment")
+			},
+			expected: "post comment timed out after 50ms",
+		},
+		{
+			description: "requests are cancelled",
+			cancel:      true,
+			call: func(ctx context.Context, c *resource.GithubClient) error {
+				return c.UpdateCommitStatus(ctx, "sha1", "", "", "success", "", "")
+			},
+			expected: "update commit status cancelled",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-done:
+				case <-r.Context().Done():
+				}
+			}))
+			defer server.Close()
+			defer close(done)
+
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:     "itsdalmo/test-repository",
+				AccessToken:    "oauthtoken",
+				V3Endpoint:     server.URL + "/",
+				V4Endpoint:     server.URL + "/graphql",
+				RequestTimeout: resource.Duration(50 * time.Millisecond),
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			assert.EqualError(t, tc.call(ctx, client), tc.expected)
+		})
+	}
+}
+
+func TestGitTimeout(t *testing.T) {
+	tests := []struct {
+		description string
+		timeout     time.Duration
+		cancel      bool
+		expected    string
+	}{
+		{
+			description: "operations time out",
+			timeout:     time.Nanosecond,
+			expected:    "git init timed out after 1ns",
+		},
+		{
+			description: "operations are cancelled",
+			timeout:     time.Minute,
+			cancel:      true,
+			expected:    "git init cancelled",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "github-pr-resource")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			git := &resource.GitClient{
+				Directory: dir,
+				Output:    ioutil.Discard,
+				Timeout:   tc.timeout,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+
+			assert.EqualError(t, git.Init(ctx, "master"), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// newTLSConfig returns the TLS configuration of the API clients, which trusts the ca_certs in addition to the
+// system roots and presents the client certificate. Returns nil when the defaults apply.
+func newTLSConfig(s *Source) (*tls.Config, error) {
+	if s.CACerts == "" && s.ClientCert == "" && !s.SkipSSLVerification {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: s.SkipSSLVerification}
+	if s.SkipSSLVerification {
+		log.Println("disabling SSL verification")
+	}
+
+	if s.CACerts != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Println("failed to load system certificates:", err)
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(s.CACerts)) {
+			return nil, errors.New("failed to parse ca_certs: no PEM encoded certificates found")
+		}
+		config.RootCAs = pool
+	}
+
+	if s.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(s.ClientCert), []byte(s.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client_cert & client_key: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// gitTLSOptions writes the ca_certs and client certificate to files for git, which only reads them from disk,
+// and returns the options which configure git to use them and the directory of the files, which is removed
+// by GitClient.Close. Note that git trusts only the ca_certs.
+func gitTLSOptions(s *Source) ([]string, string, error) {
+	if s.CACerts == "" && s.ClientCert == "" {
+		return nil, "", nil
+	}
+
+	dir, err := ioutil.TempDir("", "github-pr-resource-tls")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create certificates directory: %s", err)
+	}
+
+	var options []string
+	for _, f := range []struct {
+		option  string
+		name    string
+		content string
+	}{
+		{option: "http.sslCAInfo", name: "ca.pem", content: s.CACerts},
+		{option: "http.sslCert", name: "client.pem", content: s.ClientCert},
+		{option: "http.sslKey", name: "client-key.pem", content: s.ClientKey},
+	} {
+		if f.content == "" {
+			continue
+		}
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, []byte(f.content), 0600); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", fmt.Errorf("failed to write %s: %s", f.name, err)
+		}
+		options = append(options, "-c", f.option+"="+path)
+	}
+
+	return options, dir, nil
+}
@@ -0,0 +1,165 @@
+package resource_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+// newTestClientCertificate returns a self-signed PEM encoded client certificate and key.
+func newTestClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "concourse"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		cert
+}
+
+func TestGithubCertificates(t *testing.T) {
+	clientCert, clientKey, cert := newTestClientCertificate(t)
+
+	var peerCertificates int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerCertificates = len(r.TLS.PeerCertificates)
+		w.Write([]byte(`{"data":{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"sha1"}}}}}}`))
+	}))
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caCerts := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		description string
+		source      resource.Source
+		client      bool
+		err         string
+	}{
+		{
+			description: "untrusted certificate",
+			source:      resource.Source{},
+			err:         "certificate",
+		},
+		{
+			description: "trusted ca_certs",
+			source:      resource.Source{CACerts: caCerts},
+		},
+		{
+			description: "client certificate",
+			source:      resource.Source{CACerts: caCerts, ClientCert: clientCert, ClientKey: clientKey},
+			client:      true,
+		},
+		{
+			description: "skip ssl verification",
+			source:      resource.Source{SkipSSLVerification: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			peerCertificates = 0
+
+			source := tc.source
+			source.Repository = "itsdalmo/test-repository"
+			source.AccessToken = "oauthtoken"
+			source.V3Endpoint = server.URL + "/"
+			source.V4Endpoint = server.URL + "/graphql"
+			source.Retries = -1
+
+			client, err := resource.NewGithubClient(&source)
+			require.NoError(t, err)
+
+			_, err = client.GetPullRequest(context.Background(), 1, "")
+			if tc.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.client, peerCertificates == 1)
+			}
+		})
+	}
+}
+
+func TestGitCertificates(t *testing.T) {
+	clientCert, clientKey, _ := newTestClientCertificate(t)
+
+	git, err := resource.NewGitClient(&resource.Source{
+		Repository:  "itsdalmo/test-repository",
+		AccessToken: "oauthtoken",
+		CACerts:     clientCert,
+		ClientCert:  clientCert,
+		ClientKey:   clientKey,
+	}, "", ioutil.Discard)
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for i := 0; i+1 < len(git.Options); i += 2 {
+		assert.Equal(t, "-c", git.Options[i])
+		option := strings.SplitN(git.Options[i+1], "=", 2)
+		require.Len(t, option, 2)
+
+		b, err := ioutil.ReadFile(option[1])
+		require.NoError(t, err)
+		files[option[0]] = string(b)
+	}
+
+	assert.Equal(t, map[string]string{
+		"http.sslCAInfo": clientCert,
+		"http.sslCert":   clientCert,
+		"http.sslKey":    clientKey,
+	}, files)
+
+	// The certificates are removed once git is done
+	require.NoError(t, git.Close())
+	for i := 1; i < len(git.Options); i += 2 {
+		_, err := os.Stat(strings.SplitN(git.Options[i], "=", 2)[1])
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestInvalidCACerts(t *testing.T) {
+	source := resource.Source{Repository: "itsdalmo/test-repository", AccessToken: "oauthtoken", CACerts: "invalid"}
+	_, err := resource.NewGithubClient(&source)
+	assert.EqualError(t, err, "failed to parse ca_certs: no PEM encoded certificates found")
+}
@@ -0,0 +1,221 @@
+package resource
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secondaryRateLimitBackoff is how long a token is left unused after a secondary rate limit without Retry-After
+const secondaryRateLimitBackoff = time.Minute
+
+// tokenPool is a http.RoundTripper which authenticates each request with the token that has the most remaining
+// rate limit, as reported by the X-RateLimit-* headers. Requests which are rate limited are retried with the next
+// token, and the rate limited token is not used again until its rate limit resets.
+type tokenPool struct {
+	base   http.RoundTripper
+	mu     sync.Mutex
+	tokens []*pooledToken
+}
+
+type pooledToken struct {
+	value string
+	// remaining rate limit by resource (e.g. core or graphql), unknown until the token is used
+	remaining map[string]int
+	// limited until the rate limit resets
+	limited time.Time
+}
+
+func newTokenPool(tokens []string, base http.RoundTripper) *tokenPool {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	pool := &tokenPool{base: base}
+	for _, t := range tokens {
+		pool.tokens = append(pool.tokens, &pooledToken{value: t, remaining: make(map[string]int)})
+	}
+
+	return pool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *tokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := rateLimitResource(req)
+	tried := make(map[*pooledToken]bool, len(p.tokens))
+
+	for {
+		token := p.next(resource, tried)
+		tried[token] = true
+
+		r := req.Clone(req.Context())
+		r.Header.Set("Authorization", "Bearer "+token.value)
+		if len(tried) > 1 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		res, err := p.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+
+		limited, err := p.update(token, res)
+		if err != nil {
+			return nil, err
+		}
+
+		// The last token or a request which can not be replayed returns the rate limited response
+		if !limited || len(tried) == len(p.tokens) || (req.Body != nil && req.GetBody == nil) {
+			return res, nil
+		}
+
+		log.Printf("token %d of %d is rate limited, failing over\n", p.index(token)+1, len(p.tokens))
+		res.Body.Close()
+	}
+}
+
+// next returns the untried token which is not rate limited and has the most remaining rate limit for the resource.
+// Tokens which have not been used yet are preferred, and the token which is limited the shortest is the last resort.
+func (p *tokenPool) next(resource string, tried map[*pooledToken]bool) *pooledToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	var best *pooledToken
+	for _, t := range p.tokens {
+		if tried[t] {
+			continue
+		}
+		if best == nil {
+			best = t
+			continue
+		}
+
+		tLimited, bestLimited := t.limited.After(now), best.limited.After(now)
+		switch {
+		case tLimited != bestLimited:
+			if !tLimited {
+				best = t
+			}
+		case tLimited:
+			if t.limited.Before(best.limited) {
+				best = t
+			}
+		case remaining(t, resource) > remaining(best, resource):
+			best = t
+		}
+	}
+
+	return best
+}
+
+func remaining(t *pooledToken, resource string) int {
+	if v, ok := t.remaining[resource]; ok {
+		return v
+	}
+	return int(^uint(0) >> 1)
+}
+
+// update records the rate limit of the token from the response headers, and returns true if the response is rate limited.
+func (p *tokenPool) update(t *pooledToken, res *http.Response) (bool, error) {
+	remainingHeader := res.Header.Get("X-RateLimit-Remaining")
+	retryAfter := res.Header.Get("Retry-After")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if remainingHeader != "" {
+		if v, err := strconv.Atoi(remainingHeader); err == nil {
+			resource := res.Header.Get("X-RateLimit-Resource")
+			if resource == "" {
+				resource = rateLimitResource(res.Request)
+			}
+			t.remaining[resource] = v
+		}
+	}
+
+	limited := false
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		limited = remainingHeader == "0" || retryAfter != ""
+		if !limited {
+			// secondary rate limits are only identified by the message
+			body, err := peekBody(res)
+			if err != nil {
+				return false, err
+			}
+			limited = bytes.Contains(bytes.ToLower(body), []byte("rate limit"))
+		}
+	case http.StatusOK:
+		// GraphQL reports exceeding the rate limit as an error
+		if remainingHeader == "0" {
+			body, err := peekBody(res)
+			if err != nil {
+				return false, err
+			}
+			limited = bytes.Contains(body, []byte("RATE_LIMITED"))
+		}
+	}
+	if !limited {
+		return false, nil
+	}
+
+	switch {
+	case retryAfter != "":
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			t.limited = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	case remainingHeader == "0":
+		if reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			t.limited = time.Unix(reset, 0)
+		}
+	}
+	if !t.limited.After(time.Now()) {
+		t.limited = time.Now().Add(secondaryRateLimitBackoff)
+	}
+
+	return true, nil
+}
+
+func (p *tokenPool) index(t *pooledToken) int {
+	for i, v := range p.tokens {
+		if v == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// rateLimitResource returns the rate limit resource a request is expected to count against.
+func rateLimitResource(req *http.Request) string {
+	switch {
+	case req == nil:
+		return "core"
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	}
+	return "core"
+}
+
+// peekBody reads the body of the response, leaving it to be read again.
+func peekBody(res *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,129 @@
+package resource_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resource "github.com/telia-oss/github-pr-resource"
+)
+
+// testTokenResponse is the response of the fake GitHub for a token, or a rate limited response if remaining is 0.
+type testTokenResponse struct {
+	remaining int
+	secondary bool
+}
+
+func TestTokenPool(t *testing.T) {
+	reset := fmt.Sprintf("%d", time.Now().Add(time.Hour).Unix())
+
+	tests := []struct {
+		description string
+		responses   map[string][]testTokenResponse
+		comment     bool
+		calls       int
+		expected    []string
+		err         bool
+	}{
+		{
+			description: "uses the token with the most remaining rate limit",
+			responses: map[string][]testTokenResponse{
+				"token-a": {{remaining: 10}},
+				"token-b": {{remaining: 100}, {remaining: 99}},
+			},
+			calls:    3,
+			expected: []string{"token-a", "token-b", "token-b"},
+		},
+		{
+			description: "fails over when graphql is rate limited",
+			responses: map[string][]testTokenResponse{
+				"token-a": {{remaining: 0}},
+				"token-b": {{remaining: 100}, {remaining: 99}},
+			},
+			calls:    2,
+			expected: []string{"token-a", "token-b", "token-b"},
+		},
+		{
+			description: "fails over on a secondary rate limit and replays the request body",
+			responses: map[string][]testTokenResponse{
+				"token-a": {{remaining: 100, secondary: true}},
+				"token-b": {{remaining: 100}},
+			},
+			comment:  true,
+			calls:    1,
+			expected: []string{"token-a", "token-b"},
+		},
+		{
+			description: "returns the rate limited response when all tokens are rate limited",
+			responses: map[string][]testTokenResponse{
+				"token-a": {{remaining: 0}},
+				"token-b": {{remaining: 0}},
+			},
+			calls:    1,
+			expected: []string{"token-a", "token-b"},
+			err:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			var used []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+				used = append(used, token)
+
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				responses := tc.responses[token]
+				require.NotEmpty(t, responses, "unexpected request for %s", token)
+				response := responses[0]
+				tc.responses[token] = responses[1:]
+
+				w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(response.remaining))
+				w.Header().Set("X-RateLimit-Reset", reset)
+
+				switch {
+				case response.secondary:
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`))
+				case response.remaining == 0:
+					w.Write([]byte(`{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`))
+				case tc.comment:
+					assert.Contains(t, string(body), `"body":"comment"`)
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{}`))
+				default:
+					w.Write([]byte(`{"data":{"repository":{"pullRequest":{"number":1,"headRef":{"target":{"oid":"sha1"}}}}}}`))
+				}
+			}))
+			defer server.Close()
+
+			client, err := resource.NewGithubClient(&resource.Source{
+				Repository:   "itsdalmo/test-repository",
+				AccessTokens: []string{"token-a", "token-b"},
+				V3Endpoint:   server.URL + "/",
+				V4Endpoint:   server.URL + "/graphql",
+			})
+			require.NoError(t, err)
+
+			for i := 0; i < tc.calls; i++ {
+				if tc.comment {
+					err = client.PostComment(context.Background(), 1, "comment")
+				} else {
+					_, err = client.GetPullRequest(context.Background(), 1, "")
+				}
+				assert.Equal(t, tc.err, err != nil)
+			}
+
+			assert.Equal(t, tc.expected, used)
+		})
+	}
+}